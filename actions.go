@@ -0,0 +1,175 @@
+package compgen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+this file contains native replacements for the most common CompgenCmd actions.
+
+They read the same sources as bash, without starting a bash.
+*/
+
+// ActionGen returns a Compgen for a bash 'compgen' action (see CompgenCmd).
+//
+// Native Compgens are used for these actions:
+//
+//	command   Command names, from the $PATH.
+//	directory Directory names.
+//	export    Names of exported variables.
+//	file      File names.
+//	group     Group names, from /etc/group.
+//	hostname  Hostnames, from the $HOSTFILE or /etc/hosts.
+//	service   Service names, from /etc/services.
+//	signal    Signal names.
+//	user      User names, from /etc/passwd.
+//
+// Other actions fall back to CompgenCmd.
+func ActionGen(action string) Compgen {
+	switch action {
+	case "command":
+		return CommandGen()
+	case "directory":
+		return DirGen().Compgen()
+	case "export":
+		return ExportGen()
+	case "file":
+		return FileGen().Compgen()
+	case "group":
+		return GroupGen()
+	case "hostname":
+		return HostnameGen()
+	case "service":
+		return ServiceGen()
+	case "signal":
+		return SignalGen()
+	case "user":
+		return UserGen()
+	}
+	return CompgenCmd(action)
+}
+
+// UserGen returns a Compgen that generate user names, from /etc/passwd
+func UserGen() Compgen {
+	return tableGen("/etc/passwd", colonFields, 0, 1)
+}
+
+// GroupGen returns a Compgen that generate group names, from /etc/group
+func GroupGen() Compgen {
+	return tableGen("/etc/group", colonFields, 0, 1)
+}
+
+// HostnameGen returns a Compgen that generate hostnames, from the file in $HOSTFILE (like bash) or /etc/hosts
+func HostnameGen() Compgen {
+	return func(prefix string) []string {
+		path := os.Getenv("HOSTFILE")
+		if path == "" {
+			path = "/etc/hosts"
+		}
+		// the first field is the address, all others are names
+		return tableGen(path, strings.Fields, 1, -1)(prefix)
+	}
+}
+
+// ServiceGen returns a Compgen that generate service names, from /etc/services
+func ServiceGen() Compgen {
+	return tableGen("/etc/services", strings.Fields, 0, 1)
+}
+
+// signals are the signal names, as generated by `compgen -A signal`
+var signals = []string{
+	"SIGHUP", "SIGINT", "SIGQUIT", "SIGILL", "SIGTRAP", "SIGABRT", "SIGBUS", "SIGFPE",
+	"SIGKILL", "SIGUSR1", "SIGSEGV", "SIGUSR2", "SIGPIPE", "SIGALRM", "SIGTERM", "SIGSTKFLT",
+	"SIGCHLD", "SIGCONT", "SIGSTOP", "SIGTSTP", "SIGTTIN", "SIGTTOU", "SIGURG", "SIGXCPU",
+	"SIGXFSZ", "SIGVTALRM", "SIGPROF", "SIGWINCH", "SIGIO", "SIGPWR", "SIGSYS",
+}
+
+// SignalGen returns a Compgen that generate signal names
+func SignalGen() Compgen {
+	return ValueGen(signals)
+}
+
+// ExportGen returns a Compgen that generate the names of the environment variables
+func ExportGen() Compgen {
+	return func(prefix string) []string {
+		env := os.Environ()
+		names := make([]string, 0, len(env))
+		for _, e := range env {
+			if i := strings.Index(e, "="); i > 0 {
+				names = append(names, e[:i])
+			}
+		}
+		return ValueGen(names)(prefix)
+	}
+}
+
+// CommandGen returns a Compgen that generate the names of the executables in the $PATH
+func CommandGen() Compgen {
+	return func(prefix string) (predict []string) {
+		seen := make(map[string]bool)
+		for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+			files, err := ioutil.ReadDir(dir)
+			if err != nil { //err are ignored
+				continue
+			}
+			for _, f := range files {
+				name := f.Name()
+				if seen[name] || !strings.HasPrefix(name, prefix) {
+					continue
+				}
+				// links are followed to the actual file
+				fi, err := os.Stat(filepath.Join(dir, name))
+				if err != nil || fi.IsDir() || fi.Mode()&0111 == 0 {
+					continue
+				}
+				seen[name] = true
+				predict = append(predict, name)
+			}
+		}
+		return predict
+	}
+}
+
+// colonFields splits a line of /etc/passwd like files
+func colonFields(line string) []string { return strings.Split(line, ":") }
+
+// tableGen returns a Compgen that generate the fields[from:to] of each line of a table file (like /etc/passwd).
+//
+// Comments, NIS entries (starting with '+' or '-') and empty lines are ignored, but a '#' inside a field is kept.
+// Each value is generated only once. 'to' can be -1 for all fields.
+func tableGen(path string, split func(string) []string, from, to int) Compgen {
+	return func(prefix string) (predict []string) {
+		content, err := ioutil.ReadFile(path)
+		if err != nil { //err are ignored
+			return
+		}
+		seen := make(map[string]bool)
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.IndexAny(line[:1], "#+-") == 0 { // comments, and NIS entries (like "+@group")
+				continue
+			}
+			fields := split(line)
+			end := to
+			if end < 0 || end > len(fields) {
+				end = len(fields)
+			}
+			if from >= end {
+				continue
+			}
+			for _, f := range fields[from:end] {
+				if strings.HasPrefix(f, "#") { // a trailing comment, like in /etc/hosts
+					break
+				}
+				if f != "" && !seen[f] && strings.HasPrefix(f, prefix) {
+					seen[f] = true
+					predict = append(predict, f)
+				}
+			}
+		}
+		return predict
+	}
+}
@@ -0,0 +1,83 @@
+package compgen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tempFile writes a temporary file, and returns its path
+func tempFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "compgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.WriteString(content)
+	return f.Name()
+}
+
+func TestTableGen(t *testing.T) {
+	passwd := tempFile(t, "root:x:0:0:root:/root:/bin/bash\n# a comment\n\nrobert:x:1000:1000::/home/robert:/bin/sh\n")
+	defer os.Remove(passwd)
+
+	CheckValues(t, tableGen(passwd, colonFields, 0, 1), "r", "root", "robert")
+	CheckValues(t, tableGen(passwd, colonFields, 0, 1), "rob", "robert")
+
+	services := tempFile(t, "ssh\t22/tcp\n  ssh 22/udp # twice\nhttp 80/tcp www\n")
+	defer os.Remove(services)
+
+	CheckValues(t, tableGen(services, strings.Fields, 0, 1), "", "ssh", "http")
+	CheckValues(t, tableGen(services, strings.Fields, 2, -1), "", "www")
+
+	group := tempFile(t, "+@admins\n-nobody\nc#:x:1001:\n  # an indented comment\nusers:x:100:\n")
+	defer os.Remove(group)
+
+	CheckValues(t, tableGen(group, colonFields, 0, 1), "", "c#", "users")
+}
+
+func TestHostnameGen(t *testing.T) {
+	hosts := tempFile(t, "127.0.0.1 localhost\n::1 localhost ip6-localhost # loopback\n10.0.0.1 db db.local\n")
+	defer os.Remove(hosts)
+
+	defer os.Setenv("HOSTFILE", os.Getenv("HOSTFILE"))
+	os.Setenv("HOSTFILE", hosts)
+
+	CheckValues(t, HostnameGen(), "", "localhost", "ip6-localhost", "db", "db.local")
+	CheckValues(t, HostnameGen(), "db.", "db.local")
+}
+
+func TestExportGen(t *testing.T) {
+	os.Setenv("COMPGEN_TEST_EXPORT", "1")
+	defer os.Unsetenv("COMPGEN_TEST_EXPORT")
+
+	CheckValues(t, ExportGen(), "COMPGEN_TEST_", "COMPGEN_TEST_EXPORT")
+}
+
+func TestCommandGen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "tester"), nil, 0755)
+	ioutil.WriteFile(filepath.Join(dir, "testdata"), nil, 0644) // not executable
+	os.Mkdir(filepath.Join(dir, "testdir"), 0755)
+
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(filepath.ListSeparator)+dir)
+
+	CheckValues(t, CommandGen(), "test", "tester")
+}
+
+func TestSignalGen(t *testing.T) {
+	CheckValues(t, ActionGen("signal"), "SIGT", "SIGTRAP", "SIGTERM", "SIGTSTP", "SIGTTIN", "SIGTTOU")
+}
+
+func CheckValues(t *testing.T, gen Compgen, prefix string, x ...string) {
+	if v := gen(prefix); !EqStrings(v, x) {
+		t.Errorf("Invalid values for %q: %v vs %v", prefix, v, x)
+	}
+}
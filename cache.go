@@ -0,0 +1,199 @@
+package compgen
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+/*
+this file contains a persistent on-disk cache, for expensive generators (like listing remote branches).
+*/
+
+const (
+	CACHE_REFRESH = "COMPGEN_CACHE_REFRESH" // set in the process that refreshes stale cache entries
+)
+
+// Cache is a persistent on-disk cache of candidates, stored under $XDG_CACHE_HOME/compgen.
+//
+// Basically, you create one `NewCache` for each expensive generator, and wrap the generator:
+//
+//	cache := NewCache("git-branches", time.Hour)
+//	cache.Key(func(s State) string { return s.FlagSet.Lookup("repo").Value.String() })
+//	t.FlagState("branch", cache.Gen(branches))
+//
+// Candidates are generated for an empty prefix, cached, and then filtered by the State Matcher.
+//
+// Entries younger than the TTL are used as is. Stale entries (see Stale) are used too, but refreshed
+// by a detached process: the same command, with the same args and env, plus $COMPGEN_CACHE_REFRESH.
+// Outside of a completion (see DetectShell), like in a long running program or a test, they are refreshed
+// in the background by the current process instead.
+// Missing or expired entries are generated the same way, and there are no candidates until then.
+//
+// The refreshing process has no Terminator timeout (see Terminator.Timeout): slow generators are cached anyway.
+//
+// Cache files are written atomically: concurrent completions in multiple terminals do not corrupt them.
+type Cache struct {
+	name  string
+	ttl   time.Duration
+	stale time.Duration
+	key   func(s State) string
+}
+
+// NewCache creates a new Cache.
+//
+// 'name' identifies the generator, it must be unique among all programs (like "git-branches").
+func NewCache(name string, ttl time.Duration) *Cache {
+	return &Cache{name: name, ttl: ttl}
+}
+
+// Stale sets the duration, after the TTL, during which entries are used while being refreshed.
+func (c *Cache) Stale(d time.Duration) {
+	c.stale = d
+}
+
+// Key sets the function that compute the part of the State the candidates depend on (like the -repo value).
+//
+// By default, the candidates do not depend on the State.
+func (c *Cache) Key(key func(s State) string) {
+	c.key = key
+}
+
+// Gen returns a StateGen that caches the candidates of 'gen'
+func (c *Cache) Gen(gen StateGen) StateGen {
+	return func(s State) []Candidate {
+		prefix := s.Prefix
+		s.Prefix = "" // the cache is independent of the prefix
+		path := c.path(s)
+
+		if os.Getenv(CACHE_REFRESH) != "" { // this is the refreshing process
+			defer os.Remove(path + ".lock")
+			cands := gen(s)
+			writeCache(path, cacheEntry{Time: time.Now(), Candidates: cands})
+			return s.Matcher.orDefault().Filter(cands, prefix)
+		}
+
+		e, err := readCache(path)
+		age := time.Since(e.Time)
+		switch {
+		case err == nil && age < c.ttl: // fresh
+		case err == nil && age < c.ttl+c.stale: // stale
+			refresh(path, func() []Candidate { return gen(s) })
+		default: // missing or expired: generated like stale ones, not to exceed the completion timeout
+			refresh(path, func() []Candidate { return gen(s) })
+			return nil
+		}
+		return s.Matcher.orDefault().Filter(e.Candidates, prefix)
+	}
+}
+
+// path returns the cache file path for the State
+func (c *Cache) path(s State) string {
+	key := ""
+	if c.key != nil {
+		key = c.key(s)
+	}
+	h := sha1.Sum([]byte(key))
+	name := strings.Replace(c.name, string(filepath.Separator), "_", -1)
+	return filepath.Join(CacheDir(), name+"-"+hex.EncodeToString(h[:8])+".json")
+}
+
+// CacheDir returns the directory of the cache files: $XDG_CACHE_HOME/compgen, or ~/.cache/compgen
+func CacheDir() string {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".cache")
+	}
+	return filepath.Join(dir, "compgen")
+}
+
+// cacheEntry is the content of a cache file
+type cacheEntry struct {
+	Time       time.Time
+	Candidates []Candidate
+}
+
+func readCache(path string) (e cacheEntry, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(content, &e)
+	return
+}
+
+// writeCache writes the cache file atomically: in a temporary file, renamed once complete.
+func writeCache(path string, e cacheEntry) error {
+	content, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// refresh starts the refreshing of a cache file, unless one is already running: in a goroutine that calls
+// 'gen' outside of a completion, or in the refreshing process.
+//
+// A lock file is kept while refreshing, locks older than a minute are considered dead (the process died).
+func refresh(path string, gen func() []Candidate) {
+	lock := path + ".lock"
+	if fi, err := os.Stat(lock); err == nil && time.Since(fi.ModTime()) > time.Minute {
+		os.Remove(lock)
+	}
+	os.MkdirAll(filepath.Dir(path), 0700)
+	f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil { // already refreshing
+		return
+	}
+	f.Close()
+	if DetectShell() == NoShell { // not a completion: there is no completion process to re-execute
+		go func() {
+			defer os.Remove(lock)
+			writeCache(path, cacheEntry{Time: time.Now(), Candidates: gen()})
+		}()
+		return
+	}
+	if err := startRefresh(); err != nil {
+		os.Remove(lock)
+	}
+}
+
+// startRefresh starts the same command, with the same args and env, plus $COMPGEN_CACHE_REFRESH.
+//
+// The process is detached: it does not delay the completion. It removes the lock when done.
+var startRefresh = func() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), CACHE_REFRESH+"=1")
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
@@ -0,0 +1,172 @@
+package compgen
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+	os.Setenv("XDG_CACHE_HOME", dir)
+
+	// the refreshing process is only recorded, in a completion
+	defer os.Unsetenv(COMP_POINT)
+	defer os.Unsetenv(COMP_LINE)
+	os.Setenv(COMP_LINE, "cmd ")
+	os.Setenv(COMP_POINT, "4")
+	refreshes := 0
+	defer func(f func() error) { startRefresh = f }(startRefresh)
+	startRefresh = func() error { refreshes++; return nil }
+
+	var calls int32
+	branches := func(s State) []Candidate {
+		atomic.AddInt32(&calls, 1)
+		return NewCandidates("master", "main", s.Args[0])
+	}
+	CheckCalls := func(xcalls int32, xrefreshes int) {
+		if c := atomic.LoadInt32(&calls); c != xcalls || refreshes != xrefreshes {
+			t.Errorf("Invalid number of generator calls %v vs %v, or refreshes %v vs %v", c, xcalls, refreshes, xrefreshes)
+		}
+	}
+
+	cache := NewCache("test-branches", time.Hour)
+	cache.Stale(time.Hour)
+	cache.Key(func(s State) string { return s.Args[0] })
+	gen := cache.Gen(branches)
+	path := cache.path(State{Args: []string{"origin"}})
+
+	// missing: refreshed, and nothing until then
+	CheckCache(t, gen, State{Args: []string{"origin"}, Prefix: "ma"})
+	CheckCache(t, gen, State{Args: []string{"origin"}, Prefix: "ma"})
+	CheckCalls(0, 1)
+
+	// the refreshing process
+	os.Setenv(CACHE_REFRESH, "1")
+	CheckCache(t, gen, State{Args: []string{"origin"}, Prefix: "ma"}, "master", "main")
+	os.Unsetenv(CACHE_REFRESH)
+	if _, err := os.Stat(path + ".lock"); err == nil {
+		t.Errorf("The refreshing process did not remove the lock")
+	}
+	CheckCalls(1, 1)
+
+	// fresh
+	CheckCache(t, gen, State{Args: []string{"origin"}, Prefix: "o"}, "origin")
+	CheckCalls(1, 1)
+
+	// another key
+	CheckCache(t, gen, State{Args: []string{"upstream"}})
+	CheckCalls(1, 2)
+
+	// stale: used, and refreshed only once at a time
+	e, _ := readCache(path)
+	e.Time = e.Time.Add(-90 * time.Minute)
+	writeCache(path, e)
+	CheckCache(t, gen, State{Args: []string{"origin"}}, "master", "main", "origin")
+	CheckCache(t, gen, State{Args: []string{"origin"}}, "master", "main", "origin")
+	CheckCalls(1, 3)
+
+	os.Setenv(CACHE_REFRESH, "1")
+	CheckCache(t, gen, State{Args: []string{"origin"}}, "master", "main", "origin")
+	os.Unsetenv(CACHE_REFRESH)
+	if e, _ := readCache(path); time.Since(e.Time) > time.Minute {
+		t.Errorf("The refreshing process did not refresh the entry %v", e.Time)
+	}
+	CheckCalls(2, 3)
+
+	// expired: like missing
+	e.Time = e.Time.Add(-3 * time.Hour)
+	writeCache(path, e)
+	CheckCache(t, gen, State{Args: []string{"origin"}})
+	CheckCalls(2, 4)
+	os.Remove(path + ".lock") // the refreshing process is not started
+
+	// stale, outside of a completion: refreshed in the background
+	os.Unsetenv(COMP_LINE)
+	os.Unsetenv(COMP_POINT)
+	e.Time = e.Time.Add(3 * time.Hour)
+	writeCache(path, e)
+	CheckCache(t, gen, State{Args: []string{"origin"}}, "master", "main", "origin")
+	WaitLock(t, path)
+	if e, _ := readCache(path); time.Since(e.Time) > time.Minute {
+		t.Errorf("The background refresh did not refresh the entry %v", e.Time)
+	}
+	CheckCalls(3, 4)
+
+	// missing, outside of a completion: generated in the background
+	CheckCache(t, gen, State{Args: []string{"downstream"}})
+	WaitLock(t, cache.path(State{Args: []string{"downstream"}}))
+	CheckCache(t, gen, State{Args: []string{"downstream"}}, "master", "main", "downstream")
+	CheckCalls(4, 4)
+}
+
+// TestCacheTimeout checks that generators slower than the Terminator timeout are cached anyway.
+func TestCacheTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+	os.Setenv("XDG_CACHE_HOME", dir)
+
+	slow := func(s State) []Candidate {
+		time.Sleep(100 * time.Millisecond)
+		return NewCandidates("master", "main")
+	}
+	cache := NewCache("test-slow", time.Hour)
+	term := NewTerminator(flag.NewFlagSet("cmd", flag.ContinueOnError))
+	term.Timeout(10 * time.Millisecond)
+	term.ArgsState(cache.Gen(slow))
+	path := cache.path(State{})
+
+	// in the refreshing process, there is no timeout
+	os.Setenv(CACHE_REFRESH, "1")
+	CheckTerminatorCache(t, term, "ma", "master", "main")
+	os.Unsetenv(CACHE_REFRESH)
+	if _, err := os.Stat(path + ".lock"); err == nil {
+		t.Errorf("The refreshing process did not remove the lock")
+	}
+	CheckTerminatorCache(t, term, "ma", "master", "main")
+
+	// in the background: nothing until the cache is refreshed
+	os.Remove(path)
+	CheckTerminatorCache(t, term, "ma")
+	WaitLock(t, path)
+	CheckTerminatorCache(t, term, "ma", "master", "main")
+}
+
+func CheckTerminatorCache(t *testing.T, term *Terminator, prefix string, x ...string) {
+	comp, err := term.Compgen([]string{"cmd", prefix}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqStrings(comp, x) {
+		t.Errorf("Invalid cached values for %q: %v vs %v", prefix, comp, x)
+	}
+}
+
+// WaitLock waits for the end of a background refresh
+func WaitLock(t *testing.T, path string) {
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(path + ".lock"); err != nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("The background refresh did not remove the lock")
+}
+
+func CheckCache(t *testing.T, gen StateGen, s State, x ...string) {
+	if v := Values(gen(s)); !EqStrings(v, x) {
+		t.Errorf("Invalid cached values for %v: %v vs %v", s.Args, v, x)
+	}
+}
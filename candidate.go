@@ -0,0 +1,87 @@
+package compgen
+
+/*
+this file contains the Candidate type, for shells that can display more than values.
+*/
+
+// Candidate is a single suggestion, with its optional help text.
+//
+// Shells that cannot display descriptions or groups (bash) only use the Value.
+type Candidate struct {
+	Value       string // the actual suggestion
+	Description string // help text, like the flag usage
+	Group       string // group name, candidates are displayed by group (zsh)
+	Kind        Kind   // kind of value
+	Raw         bool   // the value is already quoted for the shell, it is never escaped (see Escape)
+}
+
+// Kind is the kind of value suggested by a Candidate
+type Kind int
+
+const (
+	KindValue     Kind = iota // any value
+	KindFlag                  // a flag name like "-name"
+	KindCommand               // a subcommand name
+	KindFile                  // a file path
+	KindDirectory             // a directory path, ending with a "/"
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindValue:
+		return "KindValue"
+	case KindFlag:
+		return "KindFlag"
+	case KindCommand:
+		return "KindCommand"
+	case KindFile:
+		return "KindFile"
+	case KindDirectory:
+		return "KindDirectory"
+	default:
+		return "<unknown>"
+	}
+}
+
+// CandidateGen is a function to generate a single kind of Candidate.
+//
+// It is the richer version of a Compgen.
+type CandidateGen func(prefix string) []Candidate
+
+// Candidates adapts a Compgen into a CandidateGen: candidates have no description.
+func (gen Compgen) Candidates() CandidateGen {
+	return func(prefix string) []Candidate {
+		return NewCandidates(gen(prefix)...)
+	}
+}
+
+// Compgen adapts a CandidateGen into a Compgen: descriptions are dropped.
+func (gen CandidateGen) Compgen() Compgen {
+	return func(prefix string) []string {
+		return Values(gen(prefix))
+	}
+}
+
+// NewCandidates returns a KindValue Candidate, without description, for each value.
+func NewCandidates(values ...string) []Candidate {
+	if values == nil {
+		return nil
+	}
+	c := make([]Candidate, len(values))
+	for i, v := range values {
+		c[i] = Candidate{Value: v}
+	}
+	return c
+}
+
+// Values returns the candidates values.
+func Values(cands []Candidate) []string {
+	if cands == nil {
+		return nil
+	}
+	v := make([]string, len(cands))
+	for i, c := range cands {
+		v[i] = c.Value
+	}
+	return v
+}
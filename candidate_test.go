@@ -0,0 +1,37 @@
+package compgen
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestCompgenCandidates(t *testing.T) {
+	gen := ValueGen([]string{"toto", "tata", "titi"}).Candidates()
+
+	c := gen("t")
+	if !EqStrings(Values(c), []string{"toto", "tata", "titi"}) {
+		t.Errorf("Invalid candidates %v", c)
+	}
+	if !EqStrings(gen.Compgen()("ta"), []string{"tata"}) {
+		t.Errorf("Invalid values %v", gen.Compgen()("ta"))
+	}
+}
+
+func TestFlagNameGenDescription(t *testing.T) {
+	fs := flag.NewFlagSet("t", flag.ContinueOnError)
+	fs.String("name", "name", "to set a name")
+	fs.Bool("yes", false, "to say yes")
+
+	if v := FlagNameGen(fs)("-na"); !EqStrings(v, []string{"-name"}) {
+		t.Errorf("Invalid flag names %v", v)
+	}
+
+	c := FlagNameCandidates(fs)("-na")
+	if len(c) != 1 {
+		t.Fatalf("Invalid number of candidates %v", c)
+	}
+	x := Candidate{Value: "-name", Description: "to set a name", Kind: KindFlag}
+	if c[0] != x {
+		t.Errorf("Invalid candidate %v vs %v", c[0], x)
+	}
+}
@@ -0,0 +1,148 @@
+package compgen
+
+import (
+	"context"
+	"flag"
+	"sort"
+)
+
+/*
+this file contains the Command tree, to complete subcommands.
+*/
+
+// Command is a Terminator with named subcommands.
+//
+// Each subcommand has its own FlagSet and Terminator, and can have subcommands too:
+//
+//	root := NewCommand("git", "the stupid content tracker", flag.CommandLine)
+//	commit := root.Sub("commit", "Record changes to the repository", commitFlags)
+//	commit.Alias("ci")
+//	commit.Flag("m", ValueGen(messages))
+//	root.Terminate()
+//
+// At position 0, subcommand names are completed:
+//
+//	$ git comm<TAB>
+//	$ git commit
+//
+// Further args are dispatched to the subcommand Terminator.
+type Command struct {
+	*Terminator
+	Name    string
+	Usage   string
+	aliases []string
+	subs    []*Command
+}
+
+// NewCommand creates a new Command, with a Terminator for 'fs'
+func NewCommand(name, usage string, fs *flag.FlagSet) *Command {
+	return &Command{
+		Terminator: NewTerminator(fs),
+		Name:       name,
+		Usage:      usage,
+	}
+}
+
+// NewFlagsCommand creates a new Command, with a Terminator for any flag package (see NewFlagsTerminator)
+func NewFlagsCommand(name, usage string, flags Flags) *Command {
+	return &Command{
+		Terminator: NewFlagsTerminator(flags),
+		Name:       name,
+		Usage:      usage,
+	}
+}
+
+// Alias registers alternative names for the command
+func (c *Command) Alias(aliases ...string) {
+	c.aliases = append(c.aliases, aliases...)
+}
+
+// Aliases returns the alternative names of the command
+func (c *Command) Aliases() []string { return c.aliases }
+
+// Sub creates and registers a new subcommand.
+//
+// Once a subcommand has been registered, the Command's varargs are dispatched to subcommands
+// (the Command's Argsgen is replaced).
+func (c *Command) Sub(name, usage string, fs *flag.FlagSet) *Command {
+	return c.sub(NewCommand(name, usage, fs))
+}
+
+// SubFlags creates and registers a new subcommand, for any flag package (see Sub and NewFlagsTerminator).
+func (c *Command) SubFlags(name, usage string, flags Flags) *Command {
+	return c.sub(NewFlagsCommand(name, usage, flags))
+}
+
+func (c *Command) sub(sub *Command) *Command {
+	c.subs = append(c.subs, sub)
+	c.Argsgen(dispatcher{c})
+	return sub
+}
+
+// Subs returns the registered subcommands
+func (c *Command) Subs() []*Command { return c.subs }
+
+// Lookup returns the subcommand called 'name' (or aliased 'name'), or nil.
+func (c *Command) Lookup(name string) *Command {
+	for _, sub := range c.subs {
+		if sub.Name == name {
+			return sub
+		}
+		for _, a := range sub.aliases {
+			if a == name {
+				return sub
+			}
+		}
+	}
+	return nil
+}
+
+// SubNameGen returns a CandidateGen that generate the subcommand names, described by their usage
+//
+// Aliases are only generated if the name itself does not match.
+func (c *Command) SubNameGen() CandidateGen {
+	return c.subNameGen(PrefixMatch)
+}
+
+func (c *Command) subNameGen(m Matcher) CandidateGen {
+	return func(prefix string) (predict []Candidate) {
+		var scores []int
+		for _, sub := range c.subs {
+			for _, n := range append([]string{sub.Name}, sub.aliases...) {
+				if score, ok := m(n, prefix); ok {
+					predict = append(predict, Candidate{Value: n, Description: sub.Usage, Group: "commands", Kind: KindCommand})
+					scores = append(scores, score)
+					break
+				}
+			}
+		}
+		sort.Stable(ranking{predict, scores})
+		return predict
+	}
+}
+
+// dispatcher is the Argsgen of a Command with subcommands.
+//
+// args[0] is the subcommand name.
+type dispatcher struct{ c *Command }
+
+func (d dispatcher) Compgen(args []string, inword bool) (comp []string, err error) {
+	cands, err := d.Candidates(args, inword)
+	return Values(cands), err
+}
+
+func (d dispatcher) Candidates(args []string, inword bool) (comp []Candidate, err error) {
+	return d.candidatesContext(context.Background(), args, inword)
+}
+
+func (d dispatcher) candidatesContext(ctx context.Context, args []string, inword bool) (comp []Candidate, err error) {
+	if pos, prefix := Prefix(args, inword); pos == 0 { // completing the subcommand name
+		return d.c.subNameGen(matcherOf(ctx))(prefix), nil
+	}
+
+	sub := d.c.Lookup(args[0])
+	if sub == nil { // unknown subcommand, there is nothing to complete
+		return
+	}
+	return sub.candidatesContext(ctx, args, inword)
+}
@@ -0,0 +1,65 @@
+package compgen
+
+import (
+	"flag"
+	"testing"
+)
+
+// gitCommand returns a small git like Command tree
+func gitCommand() *Command {
+	root := NewCommand("git", "the stupid content tracker", flag.NewFlagSet("git", flag.ContinueOnError))
+	root.fs.Bool("version", false, "print the version")
+
+	fs := flag.NewFlagSet("commit", flag.ContinueOnError)
+	fs.String("m", "", "the commit message")
+	commit := root.Sub("commit", "Record changes to the repository", fs)
+	commit.Alias("ci")
+	commit.Arg(0, ValueGen([]string{"main.go", "doc.go"}))
+
+	remote := root.Sub("remote", "Manage set of tracked repositories", flag.NewFlagSet("remote", flag.ContinueOnError))
+	remote.Sub("add", "Add a remote", flag.NewFlagSet("add", flag.ContinueOnError))
+	remote.Sub("remove", "Remove a remote", flag.NewFlagSet("remove", flag.ContinueOnError)).Alias("rm")
+	return root
+}
+
+func TestCommandLookup(t *testing.T) {
+	root := gitCommand()
+	if c := root.Lookup("ci"); c == nil || c.Name != "commit" {
+		t.Errorf("Invalid lookup for alias 'ci': %v", c)
+	}
+	if c := root.Lookup("push"); c != nil {
+		t.Errorf("Invalid lookup for unknown 'push': %v", c)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	CheckCommand(t, []string{"git", "comm"}, true, "commit")
+	CheckCommand(t, []string{"git"}, false, "commit", "remote")
+	CheckCommand(t, []string{"git", "-version", "c"}, true, "commit")
+	CheckCommand(t, []string{"git", "ci"}, true, "ci")
+	CheckCommand(t, []string{"git", "commit", "-m", "msg"}, false, "main.go", "doc.go")
+	CheckCommand(t, []string{"git", "ci", "m"}, true, "main.go")
+	CheckCommand(t, []string{"git", "commit", "-"}, true, "-m")
+	CheckCommand(t, []string{"git", "remote", "r"}, true, "remove")
+	CheckCommand(t, []string{"git", "remote", "rm", ""}, true)
+	CheckCommand(t, []string{"git", "push"}, false)
+}
+
+func CheckCommand(t *testing.T, args []string, inword bool, x ...string) {
+	// always check with a new Command, flagsets keep the parsed flags
+	comp, err := gitCommand().Compgen(args, inword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqStrings(comp, x) {
+		t.Errorf("Invalid completion for %v %v: %v vs %v", args, inword, comp, x)
+	}
+}
+
+func TestSubNameGen(t *testing.T) {
+	c := gitCommand().SubNameGen()("co")
+	x := Candidate{Value: "commit", Description: "Record changes to the repository", Group: "commands", Kind: KindCommand}
+	if len(c) != 1 || c[0] != x {
+		t.Errorf("Invalid subcommand candidates %v vs %v", c, x)
+	}
+}
@@ -2,9 +2,11 @@ package compgen
 
 import (
 	"flag"
-	"fmt"
+	"io/ioutil"
 	"os/exec"
+	"sort"
 	"strings"
+	"time"
 )
 
 /*
@@ -12,16 +14,16 @@ this file contains a few useful Compgens
 */
 
 //ValueGen returns a Compgen that filters out 'values'
+//
+// see ValueMatchGen for other matching strategies
 func ValueGen(values []string) Compgen {
-	return func(prefix string) (predict []string) {
-		//log.Printf("value gen %v", prefix)
-		f := make([]string, 0, len(values))
-		for _, v := range values {
-			if strings.HasPrefix(v, prefix) {
-				f = append(f, v)
-			}
-		}
-		return f
+	return ValueMatchGen(values, PrefixMatch)
+}
+
+//ValueStateGen returns a StateGen that filters out 'values' with the State Matcher (see Terminator.Matcher)
+func ValueStateGen(values []string) StateGen {
+	return func(s State) []Candidate {
+		return s.Matcher.orDefault().Filter(NewCandidates(values...), s.Prefix)
 	}
 }
 
@@ -29,11 +31,15 @@ func ValueGen(values []string) Compgen {
 //
 //This is pretty useless 'as is' but it's the default compgen associated with each key
 func FlagValueGen(fs *flag.FlagSet, key string) Compgen {
+	return flagValueGen(StdFlags(fs), key, PrefixMatch)
+}
+
+func flagValueGen(fs Flags, key string, m Matcher) Compgen {
 	return func(prefix string) (predict []string) {
 		// build the result
-		fs.VisitAll(func(f *flag.Flag) {
+		fs.VisitAll(func(f *Flag) {
 			if f.Name == key {
-				if strings.HasPrefix(f.DefValue, prefix) {
+				if _, ok := m(f.DefValue, prefix); ok {
 					predict = []string{f.DefValue}
 				}
 				return
@@ -47,7 +53,32 @@ func FlagValueGen(fs *flag.FlagSet, key string) Compgen {
 //
 // If the flag set has been parsed and if some values have been set, this comgen return only the not set ones.
 func FlagNameGen(fs *flag.FlagSet) Compgen {
-	return func(prefix string) (predict []string) {
+	return FlagNameCandidates(fs).Compgen()
+}
+
+// FlagNameCandidates returns a CandidateGen like FlagNameGen, but flag names are described by their usage.
+func FlagNameCandidates(fs *flag.FlagSet) CandidateGen {
+	return flagNameGen(StdFlags(fs), false, PrefixMatch)
+}
+
+// FlagEqualsNameGen returns a CandidateGen like FlagNameCandidates, but flags that need a value are generated in the `-name=` form.
+func FlagEqualsNameGen(fs *flag.FlagSet) CandidateGen {
+	return flagNameGen(StdFlags(fs), true, PrefixMatch)
+}
+
+// boolFlag is the interface of flag values that do not need a value (like -yes)
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+// isBoolFlag returns true if the flag does not need a value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(boolFlag)
+	return ok && b.IsBoolFlag()
+}
+
+func flagNameGen(fs Flags, equals bool, m Matcher) CandidateGen {
+	return func(prefix string) (predict []Candidate) {
 
 		// we need to extract the name part of the prefix (to use in compare)
 		name := strings.TrimLeft(prefix, "-")
@@ -56,24 +87,78 @@ func FlagNameGen(fs *flag.FlagSet) Compgen {
 		if dash == "" { // empty prefix lead to empty dash, this is unfortunate
 			dash = "-"
 		}
-		predict = make([]string, 0, 10)
+		gnu := fs.GNU()
+		if gnu && dash == "-" && len(name) > 1 {
+			return clusterGen(fs, prefix)
+		}
+		predict = make([]Candidate, 0, 10)
+		scores := make([]int, 0, 10)
 
-		//buid a set of already set flags
-		actual := make(map[string]interface{})
-		fs.Visit(func(f *flag.Flag) {
-			actual[f.Name] = nil
-		})
-		// build the result
-		fs.VisitAll(func(f *flag.Flag) {
-			if _, exists := actual[f.Name]; !exists && strings.HasPrefix(f.Name, name) {
-				predict = append(predict, dash+f.Name)
+		// build the result, already set flags are skipped
+		fs.VisitAll(func(f *Flag) {
+			if f.Set {
+				return
+			}
+			// GNU flags are typed as is, other flags reuse the typed dashes
+			words := []string{dash + f.Name}
+			if gnu {
+				words = f.Words
+			}
+			for _, w := range words {
+				wname := strings.TrimLeft(w, "-")
+				wdash := strings.TrimSuffix(w, wname)
+				// `--` only completes long names, `-n` only shorthands, but `-` completes both
+				if gnu && wdash != dash && !(dash == "-" && name == "") {
+					continue
+				}
+				// the name is matched without the dashes
+				if score, ok := m(wname, name); ok {
+					value := w
+					if equals && !f.IsBool && (!gnu || wdash == "--") {
+						value += "="
+					}
+					predict = append(predict, Candidate{Value: value, Description: f.Usage, Kind: KindFlag})
+					scores = append(scores, score)
+				}
 			}
 		})
 
+		sort.Stable(ranking{predict, scores})
 		return predict
 	}
 }
 
+// clusterGen completes a cluster of GNU shorthands (like `-xv`): with itself, and with the other shorthands.
+func clusterGen(fs Flags, prefix string) (predict []Candidate) {
+	used := make(map[string]bool)
+	var last *Flag
+	for i := 1; i < len(prefix); i++ {
+		if last != nil && !last.IsBool { // the rest of the word is a value
+			return nil
+		}
+		if last = fs.Lookup("-" + prefix[i:i+1]); last == nil {
+			return nil
+		}
+		used[last.Name] = true
+	}
+	predict = append(predict, Candidate{Value: prefix, Description: last.Usage, Kind: KindFlag})
+	if !last.IsBool { // the value is the next word
+		return predict
+	}
+
+	fs.VisitAll(func(f *Flag) {
+		if f.Set || used[f.Name] {
+			return
+		}
+		for _, w := range f.Words {
+			if len(w) == 2 && w[0] == '-' && w[1] != '-' { // a shorthand
+				predict = append(predict, Candidate{Value: prefix + w[1:], Description: f.Usage, Kind: KindFlag})
+			}
+		}
+	})
+	return predict
+}
+
 //CompgenCmd execute the bash builtin 'compgen' command to return values
 //
 //The action may be one of the following to generate a list of possible completions:
@@ -103,17 +188,61 @@ func FlagNameGen(fs *flag.FlagSet) Compgen {
 //    user      User names. May also be specified as -u.
 //    variable  Names of all shell variables. May also be specified as -v.
 //
+// Invalid actions generate nothing. The bash execution is killed after CompgenCmdTimeout.
+//
+// bash runs without any startup file (no .bashrc): the actions that depend on the user's interactive shell, like
+// alias, function or job, generate nothing.
+//
+// CompgenCmd starts a bash for each completion: prefer ActionGen, that uses native Compgens for the most common actions.
 func CompgenCmd(action string) Compgen {
+	if !compgenActions[action] { // not a valid action, nothing to execute
+		return func(prefix string) []string { return nil }
+	}
 
 	return func(prefix string) (predict []string) {
-		var cmd *exec.Cmd
-		cmd = exec.Command("bash", "-i", "-c", fmt.Sprintf(`compgen -A %s %s`, action, prefix))
-
-		out, err := cmd.CombinedOutput()
-		//log.Printf("%s %v", string(out), err)
-		if err != nil { //err are ignored
+		// action and prefix are passed as positional parameters: they are never interpreted by bash
+		cmd := exec.Command("bash", "--norc", "--noprofile", "-c", `compgen -A "$1" -- "$2"`, "compgen", action, prefix)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return
+		}
+		if err := cmd.Start(); err != nil {
+			return
+		}
+		// the stdout is read until closed: a child process may keep it open after bash exits, so the timeout
+		// does not wait for it
+		read := make(chan []byte, 1)
+		go func() {
+			out, _ := ioutil.ReadAll(stdout)
+			read <- out
+		}()
+		var out []byte
+		select {
+		case out = <-read:
+		case <-time.After(CompgenCmdTimeout):
+			cmd.Process.Kill()
+			cmd.Wait() // closes the stdout, and ends the reading
+			return
+		}
+		if err := cmd.Wait(); err != nil { //err are ignored (including no match)
 			return
 		}
-		return strings.Split(string(out), "\n")
+		for _, line := range strings.Split(string(out), "\n") {
+			if line != "" {
+				predict = append(predict, line)
+			}
+		}
+		return predict
 	}
 }
+
+// CompgenCmdTimeout is the maximum duration of a CompgenCmd execution
+var CompgenCmdTimeout = 2 * time.Second
+
+// compgenActions is the set of valid CompgenCmd actions
+var compgenActions = map[string]bool{
+	"alias": true, "arrayvar": true, "binding": true, "builtin": true, "command": true, "directory": true,
+	"disabled": true, "enabled": true, "export": true, "file": true, "function": true, "group": true,
+	"helptopic": true, "hostname": true, "job": true, "keyword": true, "running": true, "service": true,
+	"setopt": true, "shopt": true, "signal": true, "stopped": true, "user": true, "variable": true,
+}
@@ -0,0 +1,43 @@
+package compgen
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCompgenCmd(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+	dir, err := ioutil.TempDir("", "compgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "tester"), nil, 0644)
+
+	defer func(d time.Duration) { CompgenCmdTimeout = d }(CompgenCmdTimeout)
+
+	CheckValues(t, CompgenCmd("file"), filepath.Join(dir, "te"), filepath.Join(dir, "tester"))
+	CheckValues(t, CompgenCmd("file"), filepath.Join(dir, "zz"))
+
+	// the prefix is never executed
+	canary := filepath.Join(dir, "canary")
+	CheckValues(t, CompgenCmd("file"), "; touch "+canary)
+	CheckValues(t, CompgenCmd("file"), "$(touch "+canary+")")
+	if _, err := os.Stat(canary); err == nil {
+		t.Errorf("the prefix has been executed")
+	}
+	// neither is the action
+	CheckValues(t, CompgenCmd("file; touch "+canary), "")
+	if _, err := os.Stat(canary); err == nil {
+		t.Errorf("the action has been executed")
+	}
+
+	CompgenCmdTimeout = time.Nanosecond
+	CheckValues(t, CompgenCmd("file"), filepath.Join(dir, "te"))
+}
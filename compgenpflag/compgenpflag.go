@@ -0,0 +1,164 @@
+// compgenpflag package contains the compgen.Flags implementation for the github.com/spf13/pflag package.
+//
+// GNU style flags (like `--name` and its `-n` shorthand) are completed, and shorthands can be clustered (like `-yn`):
+//
+//	fs := pflag.NewFlagSet("cmd", pflag.ContinueOnError)
+//	fs.StringP("name", "n", "", "to set a name")
+//	t := compgenpflag.NewTerminator(fs)
+//	t.Flag("name", compgen.ValueGen(names))
+//	t.Terminate()
+//
+// Hidden flags, and deprecated flags or shorthands are not completed.
+package compgenpflag
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ericaro/compgen"
+	"github.com/spf13/pflag"
+)
+
+// NewTerminator creates a new compgen.Terminator for a pflag.FlagSet
+func NewTerminator(fs *pflag.FlagSet) *compgen.Terminator {
+	return compgen.NewFlagsTerminator(Flags(fs))
+}
+
+// NewCommand creates a new compgen.Command for a pflag.FlagSet
+func NewCommand(name, usage string, fs *pflag.FlagSet) *compgen.Command {
+	return compgen.NewFlagsCommand(name, usage, Flags(fs))
+}
+
+// Flags returns the compgen.Flags of a pflag.FlagSet
+func Flags(fs *pflag.FlagSet) compgen.Flags {
+	return flags{fs}
+}
+
+type flags struct{ fs *pflag.FlagSet }
+
+func (p flags) Parse(args []string) (compgen.Flags, error) {
+	fs := p.copy()
+	return flags{fs}, fs.Parse(args)
+}
+
+// copy returns a new FlagSet with the same flags, but textValues: the user's values are never set.
+//
+// The copy discards output and uses a permissive parsing, like the standard flags.
+// Interspersed args are always allowed (the FlagSet setting cannot be read).
+func (p flags) copy() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("terminators", pflag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.SetNormalizeFunc(p.fs.GetNormalizeFunc())
+	fs.ParseErrorsWhitelist = p.fs.ParseErrorsWhitelist
+	p.fs.VisitAll(func(f *pflag.Flag) {
+		c := fs.VarPF(&textValue{text: f.DefValue, typ: f.Value.Type(), check: checkOf(f.Value.Type())}, f.Name, f.Shorthand, f.Usage)
+		c.NoOptDefVal = f.NoOptDefVal
+		c.Hidden = f.Hidden
+		c.Deprecated = f.Deprecated
+		c.ShorthandDeprecated = f.ShorthandDeprecated
+		c.Annotations = f.Annotations
+	})
+	return fs
+}
+
+func (p flags) Args() []string { return p.fs.Args() }
+
+// Lookup returns the flag set by the word: `--name`, or `-n`.
+//
+// In a cluster of shorthands (like `-yn`), it is the first one that needs a value, the rest of the word being its value.
+// Or the last one.
+func (p flags) Lookup(word string) *compgen.Flag {
+	if strings.HasPrefix(word, "--") {
+		return p.flag(p.fs.Lookup(word[2:]))
+	}
+	if !strings.HasPrefix(word, "-") || len(word) < 2 {
+		return nil
+	}
+	var f *pflag.Flag
+	for i := 1; i < len(word); i++ {
+		if word[i] >= utf8.RuneSelf { // ShorthandLookup panics on non ASCII shorthands
+			return nil
+		}
+		if f = p.fs.ShorthandLookup(word[i : i+1]); f == nil || f.NoOptDefVal == "" {
+			break
+		}
+	}
+	return p.flag(f)
+}
+
+func (p flags) VisitAll(fn func(f *compgen.Flag)) {
+	p.fs.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden || f.Deprecated != "" {
+			return
+		}
+		fn(p.flag(f))
+	})
+}
+
+func (p flags) GNU() bool { return true }
+
+// flag converts a pflag.Flag
+func (p flags) flag(f *pflag.Flag) *compgen.Flag {
+	if f == nil {
+		return nil
+	}
+	words := []string{"--" + f.Name}
+	if f.Shorthand != "" && f.ShorthandDeprecated == "" {
+		words = append(words, "-"+f.Shorthand)
+	}
+	return &compgen.Flag{
+		Name:     f.Name,
+		Words:    words,
+		Usage:    f.Usage,
+		DefValue: f.DefValue,
+		Value:    f.Value.String(),
+		IsBool:   f.NoOptDefVal != "", // bool flags have a "true" NoOptDefVal
+		Set:      f.Changed,
+	}
+}
+
+// textValue is the pflag.Value of parsed copies: it only records the text, once checked.
+type textValue struct {
+	text  string
+	typ   string
+	check func(text string) error // nil if any text is valid
+}
+
+func (v *textValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.text
+}
+
+func (v *textValue) Set(text string) error {
+	if v.check != nil {
+		if err := v.check(text); err != nil {
+			return err
+		}
+	}
+	v.text = text
+	return nil
+}
+
+func (v *textValue) Type() string { return v.typ }
+
+// checkOf returns the check of the pflag values of a type (like "int"), or nil.
+func checkOf(typ string) func(text string) error {
+	switch typ {
+	case "bool":
+		return func(text string) error { _, err := strconv.ParseBool(text); return err }
+	case "int", "int8", "int16", "int32", "int64", "count":
+		return func(text string) error { _, err := strconv.ParseInt(text, 0, 64); return err }
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return func(text string) error { _, err := strconv.ParseUint(text, 0, 64); return err }
+	case "float32", "float64":
+		return func(text string) error { _, err := strconv.ParseFloat(text, 64); return err }
+	case "duration":
+		return func(text string) error { _, err := time.ParseDuration(text); return err }
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package compgenpflag
+
+import (
+	"testing"
+
+	"github.com/ericaro/compgen"
+	"github.com/ericaro/compgen/compgentest"
+	"github.com/spf13/pflag"
+)
+
+// newTerminator returns a Terminator for a pflag.FlagSet with shorthands
+func newTerminator() *compgen.Terminator {
+	fs := pflag.NewFlagSet("cmd", pflag.ContinueOnError)
+	fs.StringP("name", "n", "name", "to set a name")
+	fs.BoolP("yes", "y", false, "to say yes")
+	fs.String("level", "info", "the log level")
+	fs.Bool("secret", false, "a hidden flag")
+	fs.MarkHidden("secret")
+
+	t := NewTerminator(fs)
+	t.Flag("name", compgen.ValueGen([]string{"toto", "tata", "titi"}))
+	t.Arg(0, compgen.ValueGen([]string{"start", "stop"}))
+	t.ArgsState(compgen.ValueGen([]string{"now", "later"}).State())
+	return t
+}
+
+func TestFlagNames(t *testing.T) {
+	compgentest.Check(t, newTerminator(), "cmd -|", "--level", "--name", "-n", "--yes", "-y")
+	compgentest.Check(t, newTerminator(), "cmd --|", "--level", "--name", "--yes")
+	compgentest.Check(t, newTerminator(), "cmd --na|", "--name")
+	compgentest.Check(t, newTerminator(), "cmd -n|", "-n")
+	compgentest.Check(t, newTerminator(), "cmd --yes -|", "--level", "--name", "-n")
+	compgentest.Check(t, newTerminator(), "cmd --sec|")
+}
+
+func TestFlagValues(t *testing.T) {
+	compgentest.Check(t, newTerminator(), "cmd --name |", "toto", "tata", "titi")
+	compgentest.Check(t, newTerminator(), "cmd -n ta|", "tata")
+	compgentest.Check(t, newTerminator(), "cmd -yn |", "toto", "tata", "titi")
+	compgentest.Check(t, newTerminator(), "cmd --name=ti|", "titi")
+	compgentest.Check(t, newTerminator(), "cmd --level |", "info")
+}
+
+func TestArgs(t *testing.T) {
+	compgentest.Check(t, newTerminator(), "cmd -y |", "start", "stop")
+	compgentest.Check(t, newTerminator(), "cmd --yes -n toto s|", "start", "stop")
+	compgentest.Check(t, newTerminator(), "cmd -ny |", "start", "stop")
+}
+
+func TestInterspersed(t *testing.T) {
+	term := newTerminator()
+	compgentest.Check(t, term, "cmd start --yes |", "now", "later")
+	compgentest.Check(t, term, "cmd start -y |", "now", "later")
+	compgentest.Check(t, term, "cmd start -y l|", "later")
+	compgentest.Check(t, term, "cmd start --name t|", "toto", "tata", "titi")
+	compgentest.Check(t, term, "cmd start -yn |", "toto", "tata", "titi")
+	compgentest.Check(t, term, "cmd start --level=warn -|", "--name", "-n", "--yes", "-y")
+	compgentest.Check(t, term, "cmd start -- -|")
+}
+
+func TestLookup(t *testing.T) {
+	fs := pflag.NewFlagSet("cmd", pflag.ContinueOnError)
+	fs.StringP("name", "n", "name", "to set a name")
+	fs.BoolP("yes", "y", false, "to say yes")
+	flags := Flags(fs)
+
+	for word, x := range map[string]string{"--name": "name", "-n": "name", "-y": "yes", "-yn": "name", "-nyes": "name"} {
+		if f := flags.Lookup(word); f == nil || f.Name != x {
+			t.Errorf("Invalid lookup for %q: %v vs %v", word, f, x)
+		}
+	}
+	for _, word := range []string{"--x", "-x", "-", "name", "-é"} {
+		if f := flags.Lookup(word); f != nil {
+			t.Errorf("Invalid lookup for %q: %v", word, f)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	fs := pflag.NewFlagSet("cmd", pflag.ContinueOnError)
+	fs.StringP("name", "n", "name", "to set a name")
+	fs.BoolP("yes", "y", false, "to say yes")
+	fs.Int("count", 1, "the count")
+
+	flags, err := Flags(fs).Parse([]string{"-yn", "toto", "arg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := flags.Lookup("--name"); f == nil || f.Value != "toto" || !f.Set {
+		t.Errorf("Invalid parsed --name: %+v", f)
+	}
+	if args := flags.Args(); len(args) != 1 || args[0] != "arg" {
+		t.Errorf("Invalid args %v vs [arg]", args)
+	}
+	// the FlagSet itself is never set
+	if fs.Parsed() || fs.Changed("name") || fs.Lookup("name").Value.String() != "name" {
+		t.Errorf("The FlagSet has been parsed: %v", fs.Lookup("name").Value)
+	}
+	if _, err := Flags(fs).Parse([]string{"--count", "x"}); err == nil {
+		t.Errorf("Invalid parsing for --count x: no error")
+	}
+}
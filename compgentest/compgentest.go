@@ -0,0 +1,138 @@
+// compgentest package contains helper functions to test completers, without a shell.
+//
+// Test lines are command lines with a `|` cursor marker, like:
+//
+//	func TestGit(t *testing.T) {
+//		compgentest.Check(t, gitCommand(), "git comm|", "commit")
+//		compgentest.Check(t, gitCommand(), "git commit |", "main.go", "doc.go")
+//	}
+//
+// Lines with a pipe use the `‸` cursor marker instead, like "git log | grep fi‸".
+//
+// Completers are run directly, through their Complete method: the process environment is not read nor changed,
+// and os.Exit is never called.
+package compgentest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ericaro/compgen"
+)
+
+const (
+	Cursor     = "|" // the cursor marker in test lines
+	PipeCursor = "‸" // the cursor marker in test lines with a pipe: when present, `|` is a pipe
+)
+
+var (
+	ErrCursors = errors.New("More than one cursor in the line")
+)
+
+// Completer is the completion interface of a Terminator, or a Command.
+type Completer interface {
+	Complete(w io.Writer, r compgen.Request) error
+}
+
+// Parse reads a test line, and returns the bash Request, as the shell would pass it.
+//
+// If there is no cursor marker, the cursor is at the end of the line.
+func Parse(line string) (r compgen.Request, err error) {
+	cursor := Cursor
+	if strings.Contains(line, PipeCursor) {
+		cursor = PipeCursor
+	}
+	point := strings.Index(line, cursor)
+	switch {
+	case point < 0:
+		point = len(line)
+	case strings.Count(line, cursor) > 1:
+		err = ErrCursors
+		return
+	default:
+		line = line[:point] + line[point+len(cursor):]
+	}
+	return compgen.Request{Shell: compgen.Bash, Line: line, Point: point}, nil
+}
+
+// Complete runs the completer against the test line, and returns the lines read by bash:
+// candidates are escaped, and start after the last COMP_WORDBREAKS of the word (like in `-name=value`).
+func Complete(c Completer, line string) (lines []string, err error) {
+	r, err := Parse(line)
+	if err != nil {
+		return
+	}
+	return complete(c, r)
+}
+
+// complete runs the completer for the Request, and returns the output lines.
+func complete(c Completer, r compgen.Request) (lines []string, err error) {
+	var out bytes.Buffer
+	if err = c.Complete(&out, r); err != nil {
+		return
+	}
+	for _, l := range strings.Split(out.String(), "\n") {
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return
+}
+
+// Check asserts that the completer writes the 'x' lines (in order) to bash for the test line (see Complete).
+func Check(t testing.TB, c Completer, line string, x ...string) {
+	v, err := Complete(c, line)
+	if err != nil {
+		t.Fatalf("Invalid completion for %q: %v", line, err)
+	}
+	if !eqStrings(v, x) {
+		t.Errorf("Invalid completion for %q: %v vs %v", line, v, x)
+	}
+}
+
+// CheckCandidates asserts that the completer generates the 'x' candidates (in order) for the test line.
+//
+// Only the Value and the Description are compared: candidates are read as fish reads them, unescaped and with their description.
+func CheckCandidates(t testing.TB, c Completer, line string, x ...compgen.Candidate) {
+	r, err := Parse(line)
+	if err != nil {
+		t.Fatalf("Invalid completion for %q: %v", line, err)
+	}
+	// fish passes the line up to the cursor
+	lines, err := complete(c, compgen.Request{Shell: compgen.Fish, Line: r.Line[:r.Point], Point: r.Point})
+	if err != nil {
+		t.Fatalf("Invalid completion for %q: %v", line, err)
+	}
+	comp := make([]compgen.Candidate, len(lines))
+	for i, l := range lines {
+		comp[i].Value = l
+		if j := strings.Index(l, "\t"); j >= 0 {
+			comp[i].Value, comp[i].Description = l[:j], l[j+1:]
+		}
+	}
+	if len(comp) != len(x) {
+		t.Errorf("Invalid completion for %q: %v vs %v", line, comp, x)
+		return
+	}
+	for i := range x {
+		if comp[i].Value != x[i].Value || comp[i].Description != x[i].Description {
+			t.Errorf("Invalid completion for %q: %v vs %v", line, comp, x)
+			return
+		}
+	}
+}
+
+func eqStrings(v, x []string) bool {
+	if len(v) != len(x) {
+		return false
+	}
+	for i := range x {
+		if v[i] != x[i] {
+			return false
+		}
+	}
+	return true
+}
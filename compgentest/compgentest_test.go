@@ -0,0 +1,80 @@
+package compgentest
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/ericaro/compgen"
+)
+
+func TestParse(t *testing.T) {
+	CheckParse(t, "cmd to|to", []string{"cmd", "to"}, true)
+	CheckParse(t, "cmd toto |", []string{"cmd", "toto"}, false)
+	CheckParse(t, "cmd toto", []string{"cmd", "toto"}, true)
+	CheckParse(t, "cmd | toto", []string{"cmd"}, false)
+	CheckParse(t, "echo | cmd to‸to", []string{"cmd", "to"}, true)
+
+	if _, err := Parse("cmd |to|to"); err != ErrCursors {
+		t.Errorf("Invalid error for two cursors: %v", err)
+	}
+	if _, err := Parse("cmd ‸to‸to | grep to"); err != ErrCursors {
+		t.Errorf("Invalid error for two cursors: %v", err)
+	}
+}
+
+func CheckParse(t *testing.T, line string, xargs []string, xinword bool) {
+	r, err := Parse(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, inword, err := r.Args()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inword != xinword || !eqStrings(args, xargs) {
+		t.Errorf("Invalid parsing for %q: (%v,%v) vs (%v,%v)", line, args, inword, xargs, xinword)
+	}
+}
+
+// newTerminator returns a Terminator with a -name flag, and a positional arg
+func newTerminator() *compgen.Terminator {
+	fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+	fs.String("name", "name", "to set a name")
+	fs.Bool("yes", false, "to say yes")
+
+	term := compgen.NewTerminator(fs)
+	term.Flag("name", compgen.ValueGen([]string{"toto", "tata", "titi"}))
+	term.Arg(0, compgen.ValueGen([]string{"start", "stop"}))
+	return term
+}
+
+func TestCheck(t *testing.T) {
+	// the same completer for all lines
+	term := newTerminator()
+	Check(t, term, "cmd -name t|", "toto", "tata", "titi")
+	Check(t, term, "cmd -name ta|", "tata")
+	Check(t, term, "cmd -yes s|", "start", "stop")
+	Check(t, term, "cmd st|art", "start", "stop")
+	Check(t, term, "cmd start |")
+	// as read by bash: after the `=`
+	Check(t, term, "cmd -name=ti|", "titi")
+	// with a pipe, the cursor marker is PipeCursor
+	Check(t, term, "echo | cmd -yes s‸", "start", "stop")
+	Check(t, term, "cmd -yes s‸ | grep stop", "start", "stop")
+
+	CheckCandidates(t, term, "cmd -name=ti|",
+		compgen.Candidate{Value: "-name=titi"})
+	CheckCandidates(t, term, "cmd -n|",
+		compgen.Candidate{Value: "-name", Description: "to set a name", Kind: compgen.KindFlag})
+}
+
+func TestCommand(t *testing.T) {
+	root := func() *compgen.Command {
+		root := compgen.NewCommand("git", "the stupid content tracker", flag.NewFlagSet("git", flag.ContinueOnError))
+		root.Sub("commit", "Record changes to the repository", flag.NewFlagSet("commit", flag.ContinueOnError))
+		root.Sub("checkout", "Switch branches", flag.NewFlagSet("checkout", flag.ContinueOnError))
+		return root
+	}
+	Check(t, root(), "git c|", "commit", "checkout")
+	Check(t, root(), "git com|", "commit")
+}
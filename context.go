@@ -0,0 +1,86 @@
+package compgen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+/*
+this file contains the cancellable generators, for Compgens that hit the network or the disk.
+*/
+
+// ContextGen is a function to generate Candidates, that can be cancelled.
+//
+// Candidates are emitted one by one: when the Terminator timeout expires (see Terminator.Timeout),
+// the candidates already emitted are used. The generator should return as soon as the ctx is done.
+type ContextGen func(ctx context.Context, s State, emit func(Candidate))
+
+// Context adapts a StateGen into a ContextGen: candidates are emitted once they are all generated.
+func (gen StateGen) Context() ContextGen {
+	return func(ctx context.Context, s State, emit func(Candidate)) {
+		for _, c := range gen(s) {
+			emit(c)
+		}
+	}
+}
+
+// contextArgsgen is the Argsgen of this package that share the caller context (Terminator and Command).
+type contextArgsgen interface {
+	candidatesContext(ctx context.Context, args []string, inword bool) (comp []Candidate, err error)
+}
+
+// debugKey is the context key of the debug io.Writer
+type debugKey struct{}
+
+// debugf writes a debug message, if the context has a debug io.Writer (see Terminator.Debug)
+func debugf(ctx context.Context, format string, a ...interface{}) {
+	if w, ok := ctx.Value(debugKey{}).(io.Writer); ok {
+		fmt.Fprintf(w, "compgen: "+format+"\n", a...)
+	}
+}
+
+// matcherKey is the context key of the Matcher (see Terminator.Matcher)
+type matcherKey struct{}
+
+// matcherOf returns the context Matcher, or PrefixMatch
+func matcherOf(ctx context.Context) Matcher {
+	if m, ok := ctx.Value(matcherKey{}).(Matcher); ok {
+		return m
+	}
+	return PrefixMatch
+}
+
+// collect runs 'gen' until it returns or the ctx is done, and returns the candidates emitted so far.
+//
+// 'name' identifies the generator in the debug output.
+func collect(ctx context.Context, name string, gen ContextGen, s State) []Candidate {
+	var mu sync.Mutex
+	var comp []Candidate
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		gen(ctx, s, func(c Candidate) {
+			mu.Lock()
+			defer mu.Unlock()
+			comp = append(comp, c)
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		select {
+		case <-done: // finished just in time
+		default:
+			debugf(ctx, "%s generator timed out: %v", name, ctx.Err())
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the generator can still be emitting: return a copy
+	return append([]Candidate(nil), comp...)
+}
@@ -0,0 +1,67 @@
+package compgen
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowGen emits 'values' then blocks until the ctx is done, and a little more: it is still running when
+// the timeout is detected
+func slowGen(values ...string) ContextGen {
+	return func(ctx context.Context, s State, emit func(Candidate)) {
+		for _, v := range values {
+			emit(Candidate{Value: v})
+		}
+		<-ctx.Done()
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	var debug bytes.Buffer
+	term := NewTerminator(flag.NewFlagSet("t", flag.ContinueOnError))
+	term.ArgContext(0, slowGen("toto", "tata"))
+	term.ArgsState(func(s State) []Candidate {
+		time.Sleep(time.Second)
+		return NewCandidates("titi")
+	})
+	term.Timeout(50 * time.Millisecond)
+	term.Debug(&debug)
+
+	// partial results are used
+	comp, err := term.Compgen([]string{"cmd"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := []string{"toto", "tata"}; !EqStrings(comp, x) {
+		t.Errorf("Invalid partial completion %v vs %v", comp, x)
+	}
+	if !strings.Contains(debug.String(), "arg 0 generator timed out") {
+		t.Errorf("Invalid debug output %q", debug.String())
+	}
+
+	// or nothing
+	comp, _ = term.Compgen([]string{"cmd", "toto"}, false)
+	if len(comp) != 0 {
+		t.Errorf("Invalid completion after timeout %v", comp)
+	}
+	if !strings.Contains(debug.String(), "args generator timed out") {
+		t.Errorf("Invalid debug output %q", debug.String())
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	root := gitCommand()
+	root.Lookup("remote").Lookup("add").ArgContext(0, slowGen("origin"))
+	root.Timeout(50 * time.Millisecond)
+
+	// the subcommands share the root timeout
+	comp, _ := root.Compgen([]string{"git", "remote", "add"}, false)
+	if x := []string{"origin"}; !EqStrings(comp, x) {
+		t.Errorf("Invalid partial completion %v vs %v", comp, x)
+	}
+}
@@ -15,15 +15,51 @@
 //
 // Or copy the above statement in a file into  `/etc/bash_completion.d/`
 //
+// zsh users need a small hook script, that passes the `words` and `CURRENT` completion variables to the program:
+//
+//    ZshScript(os.Stdout, "cmd")
+//
+// The output can be sourced, or saved as `_cmd` in any directory of the `$fpath`.
+//
+// fish users can register it with:
+//
+//    complete -c cmd -f -k -a '(cmd __complete (commandline -cp))'
+//
+// PowerShell users need an argument completer, that passes the command AST text and the cursor position to the program:
+//
+//    PwshScript(os.Stdout, "cmd")
+//
+// A self installing program can call Completion with its arguments, so that users only need to run:
+//
+//    cmd completion bash > /etc/bash_completion.d/cmd
+//
 //
 // Usage
 //
 // Create a Terminator object, it's the basic object to runn completion on command.
 //
 // Configure the terminator by associating Compgen to flags or positional arguments.
+// A CandidateGen can be used instead, to attach a description to each suggestion, for shells that can display it.
 //
 // Terminate the command line, by printing to stdout the list of propositions.
 //
+// Commands with subcommands (like `git commit`) can use a Command tree instead: each Command embeds its own Terminator.
+// CLIs that define their options as tagged structs can build the whole Command tree with NewStructCommand.
+// Flags can follow the GNU conventions (`--name`, clustered shorthands like `-xvf`, and the `--` separator), see Terminator.GNU.
+// Other flag packages can be completed through the Flags interface, see the compgenpflag package for github.com/spf13/pflag.
+//
+// Suggestions match the word being completed by prefix, a Terminator can use a case-insensitive, substring or fuzzy Matcher instead.
+//
+// Suggestions are escaped for bash (see Escape): spaces and special characters are inserted as typed.
+// Words typed within unterminated quotes (like `cmd "my fi<TAB>`) are completed in the same quoting style.
+// Generators that already produce quoted values can mark their Candidates as Raw.
+//
+// Only the command under the cursor is completed, in pipes and lists (like `cat foo | cmd -na<TAB>`),
+// and redirection targets (like `cmd > out<TAB>`) complete files.
+// Leading env assignments and wrapper commands (like `FOO=1 sudo -E cmd -na<TAB>`) are skipped, see Terminator.Wrappers.
+//
+// Completers can be tested without a shell, see the compgentest package.
+//
 //
 //
 //
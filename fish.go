@@ -0,0 +1,77 @@
+package compgen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+/*
+this file contains the fish completion protocol.
+
+fish calls the command with a first argument set to "__complete" followed by
+the command line up to the cursor (see FishScript).
+*/
+
+const (
+	FISH_COMPLETE = "__complete" // first argument of a fish completion call
+)
+
+var (
+	ErrNotFish = errors.New("Not a fish completion call")
+)
+
+// IsFishCompletionMode returns true if the execution has been made by the fish completion script.
+//
+// More specifically:
+//
+// Returns true iif the first argument is "__complete"
+func IsFishCompletionMode() bool {
+	return len(os.Args) > 1 && os.Args[1] == FISH_COMPLETE
+}
+
+// FishArgs read the completion line from the arguments following "__complete"
+// and returns the same values as Args does for bash.
+func FishArgs() (args []string, inword bool, err error) {
+	if !IsFishCompletionMode() {
+		return nil, false, ErrNotFish
+	}
+	return fishArgs(os.Args[2:])
+}
+
+// fishArgs converts the `commandline -cp` output into args.
+func fishArgs(lines []string) (args []string, inword bool, err error) {
+	// `commandline -cp` output is split on newlines by fish
+	line := strings.Join(lines, "\n")
+	// the line stops at the cursor
+	return ParseLine(line, len(line))
+}
+
+// writeFish writes candidates in the fish format: one "value\tdescription" per line.
+func writeFish(w io.Writer, cands []Candidate) error {
+	for _, c := range cands {
+		line := c.Value
+		if c.Description != "" {
+			line += "\t" + strings.Replace(c.Description, "\n", " ", -1)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FishScript writes the fish script that registers 'cmd' as self completing.
+//
+// The script can be either sourced, or saved as `cmd.fish` in any directory of the $fish_complete_path.
+func FishScript(w io.Writer, cmd string) error {
+	_, err := fmt.Fprintf(w, fishScript, cmd, FISH_COMPLETE, ScriptPath(Fish, cmd))
+	return err
+}
+
+const fishScript = `# fish completion for %[1]s
+# save it as %[3]s (or any directory of the $fish_complete_path), or source it from config.fish
+complete -c %[1]s -f -k -a '(%[1]s %[2]s (commandline -cp))'
+`
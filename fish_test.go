@@ -0,0 +1,46 @@
+package compgen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFishArgs(t *testing.T) {
+	testFishArgs(t, []string{"tester toto"}, []string{"tester", "toto"}, true)
+	testFishArgs(t, []string{"tester toto "}, []string{"tester", "toto"}, false)
+	testFishArgs(t, []string{`tester "my fi`}, []string{"tester", "my fi"}, true)
+	testFishArgs(t, []string{"tester 'a", "b"}, []string{"tester", "a\nb"}, true)
+}
+
+func testFishArgs(t *testing.T, lines []string, xargs []string, xinwords bool) {
+	args, inwords, err := fishArgs(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inwords != xinwords || !EqStrings(args, xargs) {
+		t.Errorf("Invalid fish Args parsing (%v,%v) vs (%v,%v)", args, inwords, xargs, xinwords)
+	}
+}
+
+func TestWriteFish(t *testing.T) {
+	var buf bytes.Buffer
+	writeFish(&buf, []Candidate{{Value: "-name", Description: "to set a name", Kind: KindFlag}, {Value: "toto"}})
+
+	x := "-name\tto set a name\ntoto\n"
+	if buf.String() != x {
+		t.Errorf("Invalid fish output %q vs %q", buf.String(), x)
+	}
+}
+
+func TestFishScript(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FishScript(&buf, "tester"); err != nil {
+		t.Fatal(err)
+	}
+	x := "complete -c tester -f -k -a '(tester __complete (commandline -cp))'\n"
+	if !strings.HasSuffix(buf.String(), x) {
+		t.Errorf("Invalid fish script %q vs %q", buf.String(), x)
+	}
+}
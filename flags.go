@@ -0,0 +1,289 @@
+package compgen
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+this file contains the flag introspection interface, to complete other flag packages than the standard one.
+*/
+
+// Flag describes a flag, for completion.
+type Flag struct {
+	Name     string   // the flag name, without dashes: generators are mapped by name (see Terminator.Flag)
+	Words    []string // the words to type the flag, dashes included (like "--name" and "-n")
+	Usage    string   // the flag usage
+	DefValue string   // the default value, as text
+	Value    string   // the current value, as text
+	IsBool   bool     // true if the flag does not need a value (like -yes)
+	Set      bool     // true if the flag has been set by Parse
+}
+
+// Flags is the flag introspection interface of the Terminator.
+//
+// StdFlags implements it for the standard flag.FlagSet, other flag packages can be completed too
+// (see NewFlagsTerminator).
+type Flags interface {
+	// Parse parses the args (the command name excluded) into a copy of the Flags, and returns the parsing error if any.
+	// The copy is returned even on error, as parsed up to the error.
+	//
+	// The Flags themselves, and the user's flag values, are never set: concurrent completions can Parse the same Flags.
+	// Nothing is printed, and the process never exits.
+	Parse(args []string) (Flags, error)
+	// Args returns the non-flag args, of Flags returned by Parse.
+	Args() []string
+	// Lookup returns the flag set by the word (like "-name"), or nil.
+	Lookup(word string) *Flag
+	// VisitAll calls fn for each flag.
+	VisitAll(fn func(f *Flag))
+	// GNU returns true if flag words follow the GNU conventions: `--name` for long names, `-n` for one letter shorthands.
+	//
+	// Otherwise, flag words are a dash followed by the name, and a double dash is tolerated (like the flag package).
+	GNU() bool
+}
+
+// StdFlags returns the Flags of a standard flag.FlagSet
+func StdFlags(fs *flag.FlagSet) Flags {
+	return stdFlags{fs}
+}
+
+type stdFlags struct{ fs *flag.FlagSet }
+
+func (s stdFlags) Parse(args []string) (Flags, error) {
+	fs := s.copy()
+	return stdFlags{fs}, fs.Parse(args)
+}
+
+// copy returns a new FlagSet with the same flags, but textValues: the user's values are never set.
+//
+// The copy discards output and uses a permissive parsing.
+func (s stdFlags) copy() *flag.FlagSet {
+	fs := flag.NewFlagSet("terminators", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	s.fs.VisitAll(func(f *flag.Flag) {
+		fs.Var(&textValue{text: f.DefValue, isBool: isBoolFlag(f), check: checkOf(f.Value)}, f.Name, f.Usage)
+	})
+	return fs
+}
+
+// flagSet returns the FlagSet, for the State
+func (s stdFlags) flagSet() *flag.FlagSet { return s.fs }
+
+func (s stdFlags) Args() []string { return s.fs.Args() }
+
+func (s stdFlags) Lookup(word string) *Flag {
+	f := s.fs.Lookup(strings.TrimLeft(word, "-"))
+	if f == nil {
+		return nil
+	}
+	return s.flag(f, s.set()[f.Name])
+}
+
+func (s stdFlags) VisitAll(fn func(f *Flag)) {
+	set := s.set()
+	s.fs.VisitAll(func(f *flag.Flag) {
+		fn(s.flag(f, set[f.Name]))
+	})
+}
+
+func (s stdFlags) GNU() bool { return false }
+
+// set returns the set of flags already set
+func (s stdFlags) set() map[string]bool {
+	set := make(map[string]bool)
+	s.fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// flag converts a flag.Flag
+func (s stdFlags) flag(f *flag.Flag, set bool) *Flag {
+	return &Flag{
+		Name:     f.Name,
+		Words:    []string{"-" + f.Name},
+		Usage:    f.Usage,
+		DefValue: f.DefValue,
+		Value:    f.Value.String(),
+		IsBool:   isBoolFlag(f),
+		Set:      set,
+	}
+}
+
+// GNUFlags returns the Flags of a standard flag.FlagSet, following the GNU conventions (see Terminator.GNU).
+//
+// One letter flags are shorthands (like `-v`), that can be clustered (like `-xvf`, where f takes the rest of the word or the next one).
+// Other flags are long names (like `--name` or `--name=value`).
+// Flags can follow args, until the `--` separator: all words after it are args.
+func GNUFlags(fs *flag.FlagSet) Flags {
+	return &gnuFlags{stdFlags: stdFlags{fs}}
+}
+
+type gnuFlags struct {
+	stdFlags
+	args []string // the args remaining after Parse
+}
+
+func (g *gnuFlags) Parse(args []string) (Flags, error) {
+	p := &gnuFlags{stdFlags: stdFlags{g.copy()}}
+	return p, p.parse(args)
+}
+
+// parse parses the args into the FlagSet, following the GNU conventions
+func (g *gnuFlags) parse(args []string) error {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--": // the separator
+			g.args = append(g.args, args[i+1:]...)
+			return nil
+
+		case strings.HasPrefix(a, "--"): // a long name
+			name, value := a[2:], ""
+			eq := strings.Index(name, "=")
+			if eq >= 0 {
+				name, value = name[:eq], name[eq+1:]
+			}
+			f := g.Lookup("--" + name)
+			switch {
+			case f == nil:
+				return fmt.Errorf("flag provided but not defined: %s", a)
+			case eq >= 0:
+			case f.IsBool:
+				value = "true"
+			case i+1 < len(args):
+				i++
+				value = args[i]
+			default:
+				return fmt.Errorf("flag needs an argument: %s", a)
+			}
+			if err := g.fs.Set(f.Name, value); err != nil {
+				return err
+			}
+
+		case strings.HasPrefix(a, "-") && a != "-": // a cluster of shorthands
+			for j := 1; j < len(a); j++ {
+				f := g.Lookup("-" + a[j:j+1])
+				value := "true"
+				switch {
+				case f == nil:
+					return fmt.Errorf("flag provided but not defined: -%s", a[j:j+1])
+				case f.IsBool:
+				case j+1 < len(a): // the rest of the word is the value
+					value = a[j+1:]
+					j = len(a)
+				case i+1 < len(args):
+					i++
+					value = args[i]
+				default:
+					return fmt.Errorf("flag needs an argument: -%s", a[j:j+1])
+				}
+				if err := g.fs.Set(f.Name, value); err != nil {
+					return err
+				}
+			}
+
+		default:
+			g.args = append(g.args, a)
+		}
+	}
+	return nil
+}
+
+func (g *gnuFlags) Args() []string { return g.args }
+
+// Lookup returns the flag set by the word: `--name`, or `-n`.
+//
+// In a cluster of shorthands (like `-yn`), it is the first one that needs a value, the rest of the word being its value.
+// Or the last one.
+func (g *gnuFlags) Lookup(word string) *Flag {
+	if strings.HasPrefix(word, "--") {
+		if utf8.RuneCountInString(word[2:]) < 2 { // one letter flags are shorthands
+			return nil
+		}
+		return g.stdFlags.Lookup(word)
+	}
+	if !strings.HasPrefix(word, "-") || len(word) < 2 {
+		return nil
+	}
+	var f *Flag
+	for i := 1; i < len(word); i++ {
+		if f = g.stdFlags.Lookup(word[i : i+1]); f == nil || !f.IsBool {
+			break
+		}
+	}
+	if f != nil {
+		f.Words = gnuWords(f.Name)
+	}
+	return f
+}
+
+func (g *gnuFlags) VisitAll(fn func(f *Flag)) {
+	g.stdFlags.VisitAll(func(f *Flag) {
+		f.Words = gnuWords(f.Name)
+		fn(f)
+	})
+}
+
+func (g *gnuFlags) GNU() bool { return true }
+
+// gnuWords returns the GNU word to type a flag
+func gnuWords(name string) []string {
+	if utf8.RuneCountInString(name) == 1 {
+		return []string{"-" + name}
+	}
+	return []string{"--" + name}
+}
+
+// textValue is the flag.Value of parsed copies: it only records the text, once checked.
+type textValue struct {
+	text   string
+	isBool bool
+	check  func(text string) error // nil if any text is valid
+}
+
+func (v *textValue) String() string {
+	if v == nil { // the zero value, for flag.PrintDefaults
+		return ""
+	}
+	return v.text
+}
+
+func (v *textValue) Set(text string) error {
+	if v.check != nil {
+		if err := v.check(text); err != nil {
+			return err
+		}
+	}
+	v.text = text
+	return nil
+}
+
+func (v *textValue) IsBoolFlag() bool { return v.isBool }
+
+// checkOf returns the check of the standard flag values (like int values), or nil.
+func checkOf(value flag.Value) func(text string) error {
+	g, ok := value.(flag.Getter)
+	if !ok {
+		return nil
+	}
+	switch g.Get().(type) {
+	case bool:
+		return func(text string) error { _, err := strconv.ParseBool(text); return err }
+	case int, int64:
+		return func(text string) error { _, err := strconv.ParseInt(text, 0, 64); return err }
+	case uint, uint64:
+		return func(text string) error { _, err := strconv.ParseUint(text, 0, 64); return err }
+	case float64:
+		return func(text string) error { _, err := strconv.ParseFloat(text, 64); return err }
+	case time.Duration:
+		return func(text string) error { _, err := time.ParseDuration(text); return err }
+	}
+	return nil
+}
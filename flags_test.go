@@ -0,0 +1,139 @@
+package compgen
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+)
+
+func TestStdFlags(t *testing.T) {
+	fs := flag.NewFlagSet("t", flag.ContinueOnError)
+	fs.String("name", "name", "to set a name")
+	fs.Bool("yes", false, "to say yes")
+	flags, err := StdFlags(fs).Parse([]string{"-name", "toto", "arg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, word := range []string{"-name", "--name"} {
+		f := flags.Lookup(word)
+		if f == nil || f.Name != "name" || f.Value != "toto" || f.DefValue != "name" || !f.Set || f.IsBool {
+			t.Errorf("Invalid lookup for %q: %+v", word, f)
+		}
+	}
+	if f := flags.Lookup("-yes"); f == nil || !f.IsBool || f.Set {
+		t.Errorf("Invalid lookup for -yes: %+v", f)
+	}
+	if f := flags.Lookup("-x"); f != nil {
+		t.Errorf("Invalid lookup for -x: %+v", f)
+	}
+	if x := []string{"arg"}; !EqStrings(flags.Args(), x) {
+		t.Errorf("Invalid args %v vs %v", flags.Args(), x)
+	}
+
+	// the FlagSet itself is never set
+	if fs.Parsed() || fs.Lookup("name").Value.String() != "name" {
+		t.Errorf("The FlagSet has been parsed: %v", fs.Lookup("name").Value)
+	}
+	if _, err := StdFlags(fs).Parse([]string{"-yes=maybe"}); err == nil {
+		t.Errorf("Invalid parsing for -yes=maybe: no error")
+	}
+}
+
+// gnuFlagSet returns a FlagSet with shorthands and long names
+func gnuFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("t", flag.ContinueOnError)
+	fs.String("name", "name", "to set a name")
+	fs.String("f", "", "the file")
+	fs.Bool("x", false, "extract")
+	fs.Bool("v", false, "verbose")
+	fs.Bool("yes", false, "to say yes")
+	return fs
+}
+
+func TestGNUFlagsParse(t *testing.T) {
+	for _, c := range []struct {
+		args  []string
+		x     map[string]string // the flag values
+		xargs []string
+	}{
+		{[]string{"--name", "toto", "arg"}, map[string]string{"name": "toto"}, []string{"arg"}},
+		{[]string{"--name=toto", "--yes"}, map[string]string{"name": "toto", "yes": "true"}, nil},
+		{[]string{"-xvf", "file", "arg"}, map[string]string{"x": "true", "v": "true", "f": "file"}, []string{"arg"}},
+		{[]string{"-xffile", "-v"}, map[string]string{"x": "true", "f": "file", "v": "true"}, nil},
+		{[]string{"arg", "-v", "--", "-x", "--yes"}, map[string]string{"v": "true", "x": "false"}, []string{"arg", "-x", "--yes"}},
+		{[]string{"-", "--yes=false"}, map[string]string{"yes": "false"}, []string{"-"}},
+	} {
+		flags, err := GNUFlags(gnuFlagSet()).Parse(c.args)
+		if err != nil {
+			t.Fatalf("Invalid parsing for %v: %v", c.args, err)
+		}
+		for name, x := range c.x {
+			if v := flags.Lookup(gnuWords(name)[0]).Value; v != x {
+				t.Errorf("Invalid value for %v: %s=%q vs %q", c.args, name, v, x)
+			}
+		}
+		if !EqStrings(flags.Args(), c.xargs) {
+			t.Errorf("Invalid args for %v: %v vs %v", c.args, flags.Args(), c.xargs)
+		}
+	}
+
+	for _, args := range [][]string{{"-name"}, {"--x"}, {"-xq"}, {"--name"}, {"-xf"}, {"--yes=maybe"}} {
+		if _, err := GNUFlags(gnuFlagSet()).Parse(args); err == nil {
+			t.Errorf("Invalid parsing for %v: no error", args)
+		}
+	}
+}
+
+func TestGNU(t *testing.T) {
+	for _, c := range []struct {
+		args   []string
+		inword bool
+		x      []string
+	}{
+		{[]string{"t", "-"}, true, []string{"-f", "--name", "-v", "-x", "--yes"}},
+		{[]string{"t", "--"}, true, []string{"--name", "--yes"}},
+		{[]string{"t", "--na"}, true, []string{"--name"}},
+		{[]string{"t", "-x"}, true, []string{}}, // flags already set are not completed
+		{[]string{"t", "-xv"}, true, []string{"-xv", "-xvf"}},
+		{[]string{"t", "-xf"}, true, []string{"-xf"}},
+		{[]string{"t", "-xq"}, true, []string{}},
+		{[]string{"t", "-xf"}, false, []string{"file"}},
+		{[]string{"t", "--name"}, false, []string{"name"}},
+		{[]string{"t", "--name=n"}, true, []string{"--name=name"}},
+		{[]string{"t", "-name="}, true, []string{}}, // not a long name
+		{[]string{"t", "-x="}, true, []string{}},
+		{[]string{"t", "-xv", "a"}, true, []string{"arg"}},
+		{[]string{"t", "arg", "--y"}, true, []string{"--yes"}},
+		{[]string{"t", "--", "-"}, true, []string{}},
+		{[]string{"t", "--name", "--", "-"}, true, []string{"-f", "-v", "-x", "--yes"}},
+		// flags and args are interspersed
+		{[]string{"t", "arg", "--name", "n"}, true, []string{"name"}},
+		{[]string{"t", "arg", "-f"}, false, []string{"file"}},
+		{[]string{"t", "arg", "-xf", "f"}, true, []string{"file"}},
+		{[]string{"t", "arg", "-v"}, false, []string{"arg"}},
+		{[]string{"t", "arg", "--yes"}, false, []string{"arg"}},
+		{[]string{"t", "arg", "--yes", "a"}, true, []string{"arg"}},
+		{[]string{"t", "arg", "--name=n", "-"}, true, []string{"-f", "-v", "-x", "--yes"}},
+		{[]string{"t", "arg", "--", "-v"}, false, []string{"arg"}},
+	} {
+		term := NewTerminator(gnuFlagSet())
+		term.GNU(true)
+		term.Flag("f", ValueGen([]string{"file"}))
+		term.ArgsState(ValueGen([]string{"arg"}).State())
+		comp, err := term.Compgen(c.args, c.inword)
+		if err != nil {
+			t.Fatalf("Invalid completion for %v %v: %v", c.args, c.inword, err)
+		}
+		if !EqStrings(comp, c.x) {
+			t.Errorf("Invalid completion for %v %v: %v vs %v", c.args, c.inword, comp, c.x)
+		}
+	}
+
+	// bash reads nothing, not even an empty candidate
+	term := NewTerminator(gnuFlagSet())
+	term.GNU(true)
+	var out bytes.Buffer
+	if err := term.Complete(&out, Request{Shell: Bash, Line: "t -name=", Point: 8}); err != nil || out.Len() != 0 {
+		t.Errorf("Invalid completion for \"t -name=\": %q %v", out.String(), err)
+	}
+}
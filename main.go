@@ -1,6 +1,9 @@
 package compgen
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -11,13 +14,63 @@ const (
 	COMP_POINT = "COMP_POINT"
 )
 
-//IsCompletionMode returns true if the the execution has been made in a bash_completion environnement.
+var (
+	ErrInvalidPoint = errors.New("Invalid completion point")
+)
+
+// Shell identifies the completion protocol the execution has been made with.
+type Shell int
+
+const (
+	NoShell Shell = iota // not in completion mode
+	Bash
+	Zsh
+	Fish
+	Pwsh
+)
+
+func (s Shell) String() string {
+	switch s {
+	case NoShell:
+		return "NoShell"
+	case Bash:
+		return "Bash"
+	case Zsh:
+		return "Zsh"
+	case Fish:
+		return "Fish"
+	case Pwsh:
+		return "Pwsh"
+	default:
+		return "<unknown>"
+	}
+}
+
+// DetectShell returns the Shell whose completion protocol is used by the current execution, or NoShell.
+func DetectShell() Shell {
+	switch {
+	case os.Getenv(COMP_LINE) != "" && os.Getenv(COMP_POINT) != "":
+		return Bash
+	case IsZshCompletionMode():
+		return Zsh
+	case IsFishCompletionMode():
+		return Fish
+	case IsPwshCompletionMode():
+		return Pwsh
+	}
+	return NoShell
+}
+
+//IsCompletionMode returns true if the the execution has been made in a completion environnement.
 //
 //More specifically:
 //
-// Returns true iif  "COMP_LINE" and "COMP_POINT" are set to a non empty value
+// Returns true iif  "COMP_LINE" and "COMP_POINT" are set to a non empty value (bash)
+// or if the zsh hook variables are set (see IsZshCompletionMode)
+// or if the first argument is the fish marker (see IsFishCompletionMode)
+// or if the first argument is the PowerShell marker (see IsPwshCompletionMode)
 func IsCompletionMode() bool {
-	return os.Getenv(COMP_LINE) != "" && os.Getenv(COMP_POINT) != ""
+	return DetectShell() != NoShell
 }
 
 //CompletionLine return the completion line
@@ -48,11 +101,10 @@ func Args() (args []string, inword bool, err error) {
 	if err != nil {
 		return
 	}
-	// args is splitted so parseArgs can be tested
-	return parseArgs(os.Getenv(COMP_LINE), pos)
+	return ParseLine(os.Getenv(COMP_LINE), pos)
 }
 
-// parseArgs split the comp_line based on pos
+// ParseLine split the comp_line based on pos (a byte offset, like $COMP_POINT)
 //
 // comp_line is the bash command line
 //
@@ -61,24 +113,68 @@ func Args() (args []string, inword bool, err error) {
 // inword is true if the tab is pressed within a word "toto<TAB>" or to<TAB>to but false when "toto <TAB>"
 // the pseudo args are all args from the begining of the line up to the position. values after are NOT returned
 //
-// err is not nil if the comp_line cannot be tokenized
-func parseArgs(comp_line string, pos int) (args []string, inword bool, err error) {
+// Only the simple command the cursor is in is returned: the words following the last control operator (like `|` or `&&`),
+// without redirections (like `2> log`). Unless the cursor is on a redirection target: then args end with the operator,
+// and the target if inword (like `cmd > out<TAB>` that returns "cmd", ">", "out").
+//
+// err is not nil if the comp_line cannot be tokenized, or if pos is out of the line (ErrInvalidPoint)
+func ParseLine(comp_line string, pos int) (args []string, inword bool, err error) {
+	aargs, inword, err := parseLine(comp_line, pos)
+	args = make([]string, len(aargs))
+	for i, a := range aargs {
+		args[i] = a.Val
+	}
+	return
+}
+
+// parseLine is ParseLine, returning the Args
+func parseLine(comp_line string, pos int) (args []Arg, inword bool, err error) {
+	if pos < 0 || pos > len(comp_line) {
+		err = ErrInvalidPoint
+		return
+	}
 
 	// parse the command line upto the position
 	r := strings.NewReader(comp_line[0:pos])
 
-	aargs, err := Tokenize(r)
+	args, err = Tokenize(r)
 	if err != nil {
 		return
 	}
-	current := position(aargs, pos)
-	inword = current > 0
+	current := position(args, pos)
+	inword = current > 0 && !args[current].Op
+	args = command(args, inword)
+	// the command name is never in word
+	inword = inword && len(args) > 1
+	return
+}
 
-	args = make([]string, len(aargs))
-	for i, a := range aargs {
-		args[i] = a.Val
+// command returns the args of the last simple command, see ParseLine
+func command(args []Arg, inword bool) []Arg {
+	cmd := make([]Arg, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case !a.Op:
+			cmd = append(cmd, a)
+		case !isRedirection(a.Val): // a control operator, the next command starts
+			cmd = cmd[:0]
+		case i == len(args)-1 || i == len(args)-2 && inword: // the cursor is on the target
+			return append(cmd, args[i:]...)
+		default: // the target is not an arg
+			i++
+		}
 	}
-	return
+	return cmd
+}
+
+// redirection returns true if the cursor is on a redirection target (like `cmd > out<TAB>`), see ParseLine
+func redirection(args []Arg, inword bool) bool {
+	i := len(args) - 1
+	if inword {
+		i--
+	}
+	return i >= 0 && args[i].Op && isRedirection(args[i].Val)
 }
 
 //Prefix compute the completion prefix and position
@@ -94,14 +190,63 @@ func Prefix(args []string, inword bool) (pos int, prefix string) {
 	return
 }
 
-//read the current position from args
+//read the current position from args, pos is a byte offset (like $COMP_POINT)
 func position(args []Arg, pos int) (current int) {
 	current = -1 // if the pos is not inside any word current remains -1
 	//last := -1   // last will increase unti
 	for i, a := range args {
-		if a.Offset+a.Length >= pos && a.Offset < pos {
+		if a.ByteOffset+a.ByteLength >= pos && a.ByteOffset < pos {
 			current = i
 		}
 	}
 	return
 }
+
+// bash splits the word being completed on COMP_WORDBREAKS (the default value contains these characters)
+const wordbreaks = "=:"
+
+// writeBash writes candidates values, one per line.
+//
+// bash only replaces the part of the word after the last COMP_WORDBREAKS character (like in `-name=to`),
+// or after the open quote (like in `-name="to`), so the part before is trimmed from the values.
+//
+// Values are escaped in the quoting style of the word being completed (see Escape).
+func writeBash(w io.Writer, cands []Candidate, word Arg) error {
+	cut := word.Val[:strings.LastIndexAny(word.Val, wordbreaks)+1]
+	if word.Quote == '\'' || word.Quote == '"' {
+		cut = word.Val[:word.QuoteAt]
+	}
+	for _, c := range cands {
+		c.Value = strings.TrimPrefix(c.Value, cut)
+		if _, err := fmt.Fprintln(w, Escape(c, word.Quote)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BashScript writes the bash script that registers 'cmd' as self completing.
+//
+// The script can be either sourced, or saved in `/etc/bash_completion.d/`.
+func BashScript(w io.Writer, cmd string) error {
+	_, err := fmt.Fprintf(w, bashScript, cmd, ScriptPath(Bash, cmd))
+	return err
+}
+
+const bashScript = `# bash completion for %[1]s
+# save it as %[2]s, or source it from ~/.bashrc
+
+_%[1]s_complete() {
+	local line
+	COMPREPLY=()
+	while IFS= read -r line; do
+		COMPREPLY+=("$line")
+	done < <(COMP_LINE="$COMP_LINE" COMP_POINT="$COMP_POINT" %[1]s 2>/dev/null)
+	# no space after a single "-name=" or directory, to complete further
+	if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == *[=/] ]]; then
+		compopt -o nospace 2>/dev/null
+	fi
+}
+
+complete -F _%[1]s_complete %[1]s
+`
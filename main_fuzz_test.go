@@ -0,0 +1,46 @@
+//go:build go1.18
+// +build go1.18
+
+package compgen
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FuzzParseLine checks that multibyte runes are handled like any other rune: the args and inword
+// are the same for the line where they are replaced by ascii letters.
+func FuzzParseLine(f *testing.F) {
+	for _, line := range []string{"tester a⍅b cd", "tester été ", "cmd 'é x' \"⍅", `a\ b`} {
+		f.Add(line)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		if !utf8.ValidString(line) {
+			t.Skip()
+		}
+		ascii := strings.Map(func(r rune) rune {
+			if r >= utf8.RuneSelf && !unicode.IsSpace(r) {
+				return 'x'
+			}
+			return r
+		}, line)
+
+		pos := 0                        // the rune position
+		for point := range line + " " { // all rune boundaries, including the end of the line
+			args, inword, err := ParseLine(line, point)
+			xargs, xinword, xerr := ParseLine(ascii, len(string([]rune(ascii)[:pos])))
+			pos++
+			if (err == nil) != (xerr == nil) {
+				t.Fatalf("Invalid error for %q at %d: %v vs %v", line, point, err, xerr)
+			}
+			if err != nil {
+				continue
+			}
+			if inword != xinword || len(args) != len(xargs) {
+				t.Fatalf("Invalid args for %q at %d: (%q,%v) vs (%q,%v)", line, point, args, inword, xargs, xinword)
+			}
+		}
+	})
+}
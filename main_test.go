@@ -9,9 +9,14 @@ func TestParseArgs(t *testing.T) {
 	testArgs(t, "tester tototata", 11, []string{"tester", "toto"}, true)
 	testArgs(t, "tester toto tata", 12, []string{"tester", "toto"}, false)
 
+	for _, pos := range []int{-1, 17} {
+		if _, _, err := ParseLine("tester toto tata", pos); err != ErrInvalidPoint {
+			t.Errorf("Invalid error for point %d: %v", pos, err)
+		}
+	}
 }
 func testArgs(t *testing.T, line string, pos int, xargs []string, xinwords bool) {
-	args, inwords, err := parseArgs(line, pos)
+	args, inwords, err := ParseLine(line, pos)
 	if err != nil {
 		panic(err)
 	}
@@ -21,6 +26,46 @@ func testArgs(t *testing.T, line string, pos int, xargs []string, xinwords bool)
 	}
 }
 
+func TestParseLineCommand(t *testing.T) {
+	testArgs(t, "cat foo | tester -na", 20, []string{"tester", "-na"}, true)
+	testArgs(t, "cat foo|tester -na", 18, []string{"tester", "-na"}, true)
+	testArgs(t, "make && tester x; tester ", 25, []string{"tester"}, false)
+	testArgs(t, "tester a|", 9, []string{}, false)
+	testArgs(t, "tester a 2>/dev/null b ", 23, []string{"tester", "a", "b"}, false)
+	testArgs(t, "tester a 2>&1 >>log b", 21, []string{"tester", "a", "b"}, true)
+	testArgs(t, `tester a\;b`, 11, []string{"tester", "a;b"}, true)
+
+	// redirection targets
+	testArgs(t, "tester a > ", 11, []string{"tester", "a", ">"}, false)
+	testArgs(t, "tester a >ou", 12, []string{"tester", "a", ">", "ou"}, true)
+	testArgs(t, "tester a 2>ou", 13, []string{"tester", "a", "2>", "ou"}, true)
+	testArgs(t, "tester a &>", 11, []string{"tester", "a", "&>"}, false)
+}
+
+func TestRedirection(t *testing.T) {
+	for line, x := range map[string]bool{
+		"tester > ":      true,
+		"tester >":       true,
+		"tester > ou":    true,
+		"tester 2>ou":    true,
+		"tester > out ":  false,
+		"tester > out a": false,
+		"tester | ":      false,
+		"tester '>' ou":  false,
+		"tester a2>ou":   true, // "a2" is an arg
+		`tester 2\>ou`:   false,
+		"tester <<< ou":  true,
+	} {
+		args, inword, err := parseLine(line, len(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := redirection(args, inword); r != x {
+			t.Errorf("Invalid redirection for %q: %v vs %v", line, r, x)
+		}
+	}
+}
+
 func EqStrings(v, x []string) bool {
 	if len(v) != len(x) {
 		return false
@@ -67,3 +112,12 @@ func TestArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestParseLineMultibyte(t *testing.T) {
+	// "a⍅b" is 5 bytes long, but only 3 runes
+	line := "tester a⍅b cd"
+	testArgs(t, line, len("tester a⍅b"), []string{"tester", "a⍅b"}, true)
+	testArgs(t, line, len("tester a⍅b "), []string{"tester", "a⍅b"}, false)
+	testArgs(t, line, len("tester a⍅b c"), []string{"tester", "a⍅b", "c"}, true)
+	testArgs(t, "tester été ", len("tester été "), []string{"tester", "été"}, false)
+}
@@ -0,0 +1,139 @@
+package compgen
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+this file contains the matching strategies, to select the values that match the word being completed.
+*/
+
+// Matcher returns true if 'value' matches the 'prefix' typed by the user, with a score: the higher the better.
+//
+// Scores are only comparable between values matched by the same Matcher.
+type Matcher func(value, prefix string) (score int, ok bool)
+
+// PrefixMatch is the default Matcher: values must start with the prefix.
+func PrefixMatch(value, prefix string) (score int, ok bool) {
+	return 0, strings.HasPrefix(value, prefix)
+}
+
+// FoldPrefixMatch is a case-insensitive PrefixMatch. Values with the same case score first.
+func FoldPrefixMatch(value, prefix string) (score int, ok bool) {
+	if strings.HasPrefix(value, prefix) {
+		return 1, true
+	}
+	// rune by rune: case folding can change the length in bytes (like 'ſ' and 's')
+	for _, p := range prefix {
+		v, size := utf8.DecodeRuneInString(value)
+		if size == 0 || !strings.EqualFold(string(v), string(p)) {
+			return 0, false
+		}
+		value = value[size:]
+	}
+	return 0, true
+}
+
+// SubstringMatch is a Matcher where values must contain the prefix. The sooner, the better.
+func SubstringMatch(value, prefix string) (score int, ok bool) {
+	i := strings.Index(value, prefix)
+	return -i, i >= 0
+}
+
+// FuzzyMatch is a case-insensitive Matcher where values must contain all the prefix characters, in order.
+//
+// Consecutive characters, and characters at the beginning of the value or of a word score first, gaps score last.
+// So that "fb" matches "foo-bar" better than "xfxb".
+func FuzzyMatch(value, prefix string) (score int, ok bool) {
+	last := -1 // the index of the last matched rune in value
+	i := 0     // the index of the rune in value
+	prev := rune(0)
+	p := []rune(strings.ToLower(prefix))
+	j := 0 // the index of the next rune to match in p
+
+	for _, r := range value {
+		if j < len(p) && unicode.ToLower(r) == p[j] {
+			switch {
+			case i == 0: // start of the value
+				score += 8
+			case last == i-1: // consecutive
+				score += 5
+			case isWordStart(prev, r):
+				score += 3
+			default: // after a gap
+				score -= i - last - 1
+			}
+			if r == []rune(prefix)[j] { // same case
+				score++
+			}
+			last = i
+			j++
+		}
+		prev = r
+		i++
+	}
+	return score, j == len(p)
+}
+
+// isWordStart returns true if 'r' starts a word, after 'prev'
+func isWordStart(prev, r rune) bool {
+	return !unicode.IsLetter(prev) && !unicode.IsDigit(prev) || unicode.IsLower(prev) && unicode.IsUpper(r)
+}
+
+// orDefault returns the Matcher, or PrefixMatch if nil
+func (m Matcher) orDefault() Matcher {
+	if m == nil {
+		return PrefixMatch
+	}
+	return m
+}
+
+// Filter returns the candidates whose value matches 'prefix', best first.
+func (m Matcher) Filter(cands []Candidate, prefix string) []Candidate {
+	f := make([]Candidate, 0, len(cands))
+	scores := make([]int, 0, len(cands))
+	for _, c := range cands {
+		if score, ok := m(c.Value, prefix); ok {
+			f = append(f, c)
+			scores = append(scores, score)
+		}
+	}
+	sort.Stable(ranking{f, scores})
+	return f
+}
+
+// Rank sorts the candidates, best match of 'prefix' first. Candidates that do not match are kept last.
+func (m Matcher) Rank(cands []Candidate, prefix string) {
+	scores := make([]int, len(cands))
+	for i, c := range cands {
+		score, ok := m(c.Value, prefix)
+		if !ok {
+			score = -utf8.RuneCountInString(c.Value) - 1<<20
+		}
+		scores[i] = score
+	}
+	sort.Stable(ranking{cands, scores})
+}
+
+// ranking sorts candidates by decreasing scores
+type ranking struct {
+	cands  []Candidate
+	scores []int
+}
+
+func (r ranking) Len() int           { return len(r.cands) }
+func (r ranking) Less(i, j int) bool { return r.scores[i] > r.scores[j] }
+func (r ranking) Swap(i, j int) {
+	r.cands[i], r.cands[j] = r.cands[j], r.cands[i]
+	r.scores[i], r.scores[j] = r.scores[j], r.scores[i]
+}
+
+// ValueMatchGen returns a Compgen that filters out 'values' using the Matcher, best first
+func ValueMatchGen(values []string, m Matcher) Compgen {
+	return func(prefix string) []string {
+		return Values(m.Filter(NewCandidates(values...), prefix))
+	}
+}
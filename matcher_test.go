@@ -0,0 +1,110 @@
+package compgen
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestMatchers(t *testing.T) {
+	values := []string{"Makefile", "main.go", "doc.go", "mock_main.go"}
+
+	CheckMatcher(t, PrefixMatch, values, "ma", "main.go")
+	CheckMatcher(t, FoldPrefixMatch, values, "ma", "main.go", "Makefile")
+	CheckMatcher(t, FoldPrefixMatch, values, "MAK", "Makefile")
+	// case folding can change the length in bytes
+	CheckMatcher(t, FoldPrefixMatch, []string{"ſx", "Kelvin"}, "sx", "ſx")
+	CheckMatcher(t, FoldPrefixMatch, []string{"ſx", "Kelvin"}, "\u212Ael", "Kelvin")
+	CheckMatcher(t, FoldPrefixMatch, []string{"ſ"}, "sx")
+	CheckMatcher(t, SubstringMatch, values, "ma", "main.go", "mock_main.go")
+	CheckMatcher(t, SubstringMatch, values, "go", "doc.go", "main.go", "mock_main.go")
+	CheckMatcher(t, FuzzyMatch, values, "mg", "main.go", "mock_main.go")
+	CheckMatcher(t, FuzzyMatch, values, "mm", "mock_main.go")
+	CheckMatcher(t, FuzzyMatch, values, "", values...)
+	CheckMatcher(t, FuzzyMatch, values, "xyz")
+}
+
+func TestFuzzyMatchScore(t *testing.T) {
+	// word starts score better than gaps
+	CheckMatcher(t, FuzzyMatch, []string{"xfxb", "foo-bar"}, "fb", "foo-bar", "xfxb")
+	// and consecutive characters too
+	CheckMatcher(t, FuzzyMatch, []string{"xfxoxo", "xfoo"}, "foo", "xfoo", "xfxoxo")
+	// camel case is a word start
+	CheckMatcher(t, FuzzyMatch, []string{"xfxxb", "fooBar"}, "fb", "fooBar", "xfxxb")
+	// the same case scores first
+	CheckMatcher(t, FuzzyMatch, []string{"Foo", "foo"}, "f", "foo", "Foo")
+}
+
+func CheckMatcher(t *testing.T, m Matcher, values []string, prefix string, x ...string) {
+	comp := ValueMatchGen(values, m)(prefix)
+	if !EqStrings(comp, x) {
+		t.Errorf("Invalid match for %q: %v vs %v", prefix, comp, x)
+	}
+}
+
+func TestMatcherRank(t *testing.T) {
+	cands := NewCandidates("xaxb", "other", "ab")
+	Matcher(FuzzyMatch).Rank(cands, "ab")
+	if x := []string{"ab", "xaxb", "other"}; !EqStrings(Values(cands), x) {
+		t.Errorf("Invalid rank %v vs %v", Values(cands), x)
+	}
+}
+
+func TestTerminatorMatcher(t *testing.T) {
+	term := func() *Terminator {
+		fs := flag.NewFlagSet("t", flag.ContinueOnError)
+		fs.String("name", "name", "to set a name")
+		fs.String("user-name", "", "to set a user name")
+		fs.String("Level", "info", "the log level")
+
+		term := NewTerminator(fs)
+		term.Matcher(FuzzyMatch)
+		term.FlagState("user-name", ValueStateGen([]string{"root", "robert"}))
+		term.ArgsState(func(s State) []Candidate {
+			return s.Matcher.Filter(NewCandidates("README.md", "readme.txt"), s.Prefix)
+		})
+		return term
+	}
+
+	for _, c := range []struct {
+		args   []string
+		inword bool
+		x      []string
+	}{
+		{[]string{"t", "-nm"}, true, []string{"-name", "-user-name"}},
+		{[]string{"t", "-un"}, true, []string{"-user-name"}},
+		{[]string{"t", "-lev"}, true, []string{"-Level"}},
+		{[]string{"t", "-Level", "nf"}, true, []string{"info"}},
+		{[]string{"t", "-Level=nf"}, true, []string{"-Level=info"}},
+		{[]string{"t", "-user-name", "rbt"}, true, []string{"robert"}},
+		{[]string{"t", "rdm"}, true, []string{"readme.txt", "README.md"}},
+	} {
+		comp, err := term().Compgen(c.args, c.inword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !EqStrings(comp, c.x) {
+			t.Errorf("Invalid completion for %v %v: %v vs %v", c.args, c.inword, comp, c.x)
+		}
+	}
+}
+
+func TestCommandMatcher(t *testing.T) {
+	// subcommands inherit the root Matcher
+	for _, c := range []struct {
+		args []string
+		x    []string
+	}{
+		{[]string{"git", "mt"}, []string{"remote", "commit"}},
+		{[]string{"git", "remote", "rmv"}, []string{"remove"}},
+	} {
+		root := gitCommand()
+		root.Matcher(FuzzyMatch)
+		comp, err := root.Compgen(c.args, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !EqStrings(comp, c.x) {
+			t.Errorf("Invalid completion for %v: %v vs %v", c.args, comp, c.x)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package compgen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+this file contains native file and directory Compgens (no need to call `compgen -A file`)
+*/
+
+// FileGen returns a CandidateGen that generate file and directory names, relative to the prefix.
+//
+// Directories are generated with a trailing "/", so that they can be completed further.
+// Hidden files (starting with a '.') are only generated if the prefix base starts with a '.' too.
+//
+// If patterns are given (like "*.yaml", see filepath.Match), only matching files are generated.
+// Directories are always generated.
+//
+// Caveat: bash appends a space after a single suggestion, unless registered with `complete -o nospace -C cmd cmd`
+func FileGen(patterns ...string) CandidateGen {
+	return pathGen(false, patterns)
+}
+
+// DirGen returns a CandidateGen that generate only directory names, relative to the prefix.
+//
+// see FileGen
+func DirGen() CandidateGen {
+	return pathGen(true, nil)
+}
+
+func pathGen(dirsOnly bool, patterns []string) CandidateGen {
+	return func(prefix string) (predict []Candidate) {
+		// the prefix is made of the directory to read and the base to compare
+		dir, base := filepath.Split(prefix)
+
+		read := dir
+		switch {
+		case read == "":
+			read = "."
+		case strings.HasPrefix(read, "~/"): // the shell did not expand it
+			read = filepath.Join(os.Getenv("HOME"), read[2:])
+		}
+
+		files, err := ioutil.ReadDir(read)
+		if err != nil { //err are ignored
+			return
+		}
+
+		for _, f := range files {
+			name := f.Name()
+			if !strings.HasPrefix(name, base) {
+				continue
+			}
+			if strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") { // hidden file
+				continue
+			}
+
+			isDir := f.IsDir()
+			if f.Mode()&os.ModeSymlink != 0 { // follow links to directories
+				if fi, err := os.Stat(filepath.Join(read, name)); err == nil {
+					isDir = fi.IsDir()
+				}
+			}
+
+			switch {
+			case isDir:
+				predict = append(predict, Candidate{Value: dir + name + "/", Kind: KindDirectory})
+			case !dirsOnly && matchAny(patterns, name):
+				predict = append(predict, Candidate{Value: dir + name, Kind: KindFile})
+			}
+		}
+		return predict
+	}
+}
+
+// matchAny returns true if 'name' matches any of the patterns, or if there are no patterns.
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
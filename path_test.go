@@ -0,0 +1,63 @@
+package compgen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tempTree creates a small file tree, and returns its root (ending with a '/')
+func tempTree(t *testing.T) string {
+	root, err := ioutil.TempDir("", "compgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{"conf", "data", ".git"} {
+		os.Mkdir(filepath.Join(root, d), 0755)
+	}
+	for _, f := range []string{"config.yaml", "config.json", ".hidden", "conf/app.yaml"} {
+		ioutil.WriteFile(filepath.Join(root, f), nil, 0644)
+	}
+	os.Symlink(filepath.Join(root, "data"), filepath.Join(root, "link"))
+	return root + "/"
+}
+
+func TestFileGen(t *testing.T) {
+	root := tempTree(t)
+	defer os.RemoveAll(root)
+
+	CheckPath(t, FileGen(), root, "con", "conf/", "config.json", "config.yaml")
+	CheckPath(t, FileGen(), root, "conf/", "conf/app.yaml")
+	CheckPath(t, FileGen(), root, "l", "link/")
+	CheckPath(t, FileGen(), root, ".", ".git/", ".hidden")
+	CheckPath(t, FileGen(), root, "z")
+	CheckPath(t, FileGen("*.yaml"), root, "con", "conf/", "config.yaml")
+	CheckPath(t, DirGen(), root, "", "conf/", "data/", "link/")
+}
+
+// CheckPath checks the paths generated for root+prefix, x are relative to root
+func CheckPath(t *testing.T, gen CandidateGen, root, prefix string, x ...string) {
+	prefix = root + prefix
+	for i := range x {
+		x[i] = root + x[i]
+	}
+	if !EqStrings(Values(gen(prefix)), x) {
+		t.Errorf("Invalid paths for %q: %v vs %v", prefix, Values(gen(prefix)), x)
+	}
+}
+
+func TestFileGenKind(t *testing.T) {
+	root := tempTree(t)
+	defer os.RemoveAll(root)
+
+	for _, c := range FileGen()(root + "conf") {
+		x := KindFile
+		if c.Value == root+"conf/" {
+			x = KindDirectory
+		}
+		if c.Kind != x {
+			t.Errorf("Invalid kind for %q: %v vs %v", c.Value, c.Kind, x)
+		}
+	}
+}
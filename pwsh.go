@@ -0,0 +1,151 @@
+package compgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+/*
+this file contains the PowerShell completion protocol.
+
+The argument completer (see PwshScript) calls the command with a first argument set to "__complete_pwsh"
+followed by the cursor position (in UTF-16 code units, like .NET strings), and the command AST text in $COMP_PWSH_LINE.
+
+The text is passed in the env: before PowerShell 7.3, double quotes are mangled in native command arguments.
+*/
+
+const (
+	PWSH_COMPLETE = "__complete_pwsh" // first argument of a PowerShell completion call
+	PWSH_LINE     = "COMP_PWSH_LINE"  // the command AST text
+)
+
+var (
+	ErrNotPwsh = errors.New("Not a PowerShell completion call")
+)
+
+// IsPwshCompletionMode returns true if the execution has been made by the PowerShell argument completer.
+//
+// More specifically:
+//
+// Returns true iif the first argument is "__complete_pwsh"
+func IsPwshCompletionMode() bool {
+	return len(os.Args) > 1 && os.Args[1] == PWSH_COMPLETE
+}
+
+// PwshArgs read the cursor position from the argument following "__complete_pwsh", and the command line ($COMP_PWSH_LINE) from env
+// and returns the same values as Args does for bash.
+func PwshArgs() (args []string, inword bool, err error) {
+	if !IsPwshCompletionMode() || len(os.Args) < 3 {
+		return nil, false, ErrNotPwsh
+	}
+	pos, err := strconv.Atoi(os.Args[2])
+	if err != nil {
+		return
+	}
+	return pwshArgs(os.Getenv(PWSH_LINE), pos)
+}
+
+// pwshArgs converts the command AST text and the cursor position into args.
+//
+// pos is a position in UTF-16 code units (like .NET strings), relative to the beginning of the command.
+func pwshArgs(line string, pos int) (args []string, inword bool, err error) {
+	if pos < 0 {
+		pos = 0
+	}
+	text := utf16.Encode([]rune(line))
+	// the AST extent does not contain trailing spaces, but the cursor can be after them
+	if n := len(text); pos > n {
+		line += strings.Repeat(" ", pos-n)
+		text = utf16.Encode([]rune(line))
+	}
+	// ParseLine expects a byte position
+	pos = len(string(utf16.Decode(text[:pos])))
+	return ParseLine(line, pos)
+}
+
+// writePwsh writes candidates in a CompletionResult friendly format: one "text\tlistItem\ttype\ttooltip" per line.
+//
+// The text is quoted if needed (see pwshQuote), the listItem is the value as is.
+// The tooltip cannot be empty, it defaults to the value. Candidates with an empty text are dropped (CompletionResult rejects them).
+func writePwsh(w io.Writer, cands []Candidate) error {
+	for _, c := range cands {
+		if c.Value == "" {
+			continue
+		}
+		tip := strings.Replace(c.Description, "\n", " ", -1)
+		if tip == "" {
+			tip = c.Value
+		}
+		text := c.Value
+		if !c.Raw {
+			text = pwshQuote(text)
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", text, c.Value, pwshResultType(c.Kind), tip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pwshSpecials are the characters that cannot be typed as is in a PowerShell word.
+const pwshSpecials = " \t\n'\"`$@#;,|&(){}<>\u2018\u2019\u201a\u201b\u201c\u201d\u201e"
+
+// pwshQuote returns the value as a single quoted PowerShell string, if it contains special characters:
+// within single quotes, the single quotes (including the typographic ones) are doubled.
+func pwshQuote(v string) string {
+	if !strings.ContainsAny(v, pwshSpecials) {
+		return v
+	}
+	var b bytes.Buffer
+	b.WriteByte('\'')
+	for _, r := range v {
+		if strings.ContainsRune("'\u2018\u2019\u201a\u201b", r) {
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// pwshResultType returns the CompletionResultType for a Kind.
+func pwshResultType(k Kind) string {
+	switch k {
+	case KindFlag:
+		return "ParameterName"
+	case KindFile:
+		return "ProviderItem"
+	case KindDirectory:
+		return "ProviderContainer"
+	default:
+		return "ParameterValue"
+	}
+}
+
+// PwshScript writes the PowerShell script that registers 'cmd' as self completing.
+//
+// The script can be either dot sourced, or appended to the $PROFILE.
+func PwshScript(w io.Writer, cmd string) error {
+	_, err := fmt.Fprintf(w, pwshScript, cmd, PWSH_COMPLETE, PWSH_LINE)
+	return err
+}
+
+const pwshScript = "# PowerShell completion for %[1]s\n" +
+	"# dot source it from your $PROFILE\n" +
+	"Register-ArgumentCompleter -Native -CommandName '%[1]s' -ScriptBlock {\n" +
+	"	param($wordToComplete, $commandAst, $cursorPosition)\n" +
+	"	$pos = $cursorPosition - $commandAst.Extent.StartOffset\n" +
+	"	$env:%[3]s = $commandAst.Extent.Text\n" +
+	"	$lines = & '%[1]s' %[2]s $pos 2>$null\n" +
+	"	Remove-Item Env:%[3]s\n" +
+	"	foreach ($line in $lines) {\n" +
+	"		$text, $item, $type, $tip = $line -split \"`t\"\n" +
+	"		[System.Management.Automation.CompletionResult]::new($text, $item, $type, $tip)\n" +
+	"	}\n" +
+	"}\n"
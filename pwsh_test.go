@@ -0,0 +1,91 @@
+package compgen
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPwshArgs(t *testing.T) {
+	testPwshArgs(t, "tester toto", 11, []string{"tester", "toto"}, true)
+	testPwshArgs(t, "tester tototata", 11, []string{"tester", "toto"}, true)
+	testPwshArgs(t, "tester toto", 12, []string{"tester", "toto"}, false) // cursor after the AST extent
+	// positions are in UTF-16 code units: 😀 is two of them
+	testPwshArgs(t, "tester 😀 tototata", 14, []string{"tester", "😀", "toto"}, true)
+	testPwshArgs(t, "tester é😀", 10, []string{"tester", "é😀"}, true)
+	testPwshArgs(t, "tester 😀", 11, []string{"tester", "😀"}, false)
+}
+
+func testPwshArgs(t *testing.T, line string, pos int, xargs []string, xinwords bool) {
+	args, inwords, err := pwshArgs(line, pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inwords != xinwords || !EqStrings(args, xargs) {
+		t.Errorf("Invalid PowerShell Args parsing (%v,%v) vs (%v,%v)", args, inwords, xargs, xinwords)
+	}
+}
+
+func TestWritePwsh(t *testing.T) {
+	var buf bytes.Buffer
+	writePwsh(&buf, []Candidate{{Value: "-name", Description: "to set a name", Kind: KindFlag}, {Value: ""}, {Value: "toto"},
+		{Value: "my file.txt", Kind: KindFile}, {Value: "it's"}, {Value: "$a b", Raw: true}})
+
+	x := "-name\t-name\tParameterName\tto set a name\ntoto\ttoto\tParameterValue\ttoto\n" +
+		"'my file.txt'\tmy file.txt\tProviderItem\tmy file.txt\n'it''s'\tit's\tParameterValue\tit's\n$a b\t$a b\tParameterValue\t$a b\n"
+	if buf.String() != x {
+		t.Errorf("Invalid PowerShell output %q vs %q", buf.String(), x)
+	}
+}
+
+func TestPwshQuote(t *testing.T) {
+	for v, x := range map[string]string{
+		"toto":      "toto",
+		"-name":     "-name",
+		"my file":   "'my file'",
+		"it's":      "'it''s'",
+		"it\u2019s": "'it\u2019\u2019s'",
+		"$HOME":     "'$HOME'",
+		"a;b":       "'a;b'",
+		"~/dir/":    "~/dir/",
+		"C:\\Users": "C:\\Users",
+	} {
+		if q := pwshQuote(v); q != x {
+			t.Errorf("Invalid PowerShell quoting of %q: %q vs %q", v, q, x)
+		}
+	}
+}
+
+func TestPwshScript(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PwshScript(&buf, "tester"); err != nil {
+		t.Fatal(err)
+	}
+	script := buf.String()
+	for _, x := range []string{"Register-ArgumentCompleter -Native -CommandName 'tester'", "$env:COMP_PWSH_LINE = $commandAst.Extent.Text", "& 'tester' __complete_pwsh $pos", "CompletionResult"} {
+		if !strings.Contains(script, x) {
+			t.Errorf("PowerShell script does not contain %q", x)
+		}
+	}
+}
+
+func TestPwshRequest(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"tester", PWSH_COMPLETE, "11"}
+	os.Setenv(PWSH_LINE, `tester "a b`)
+	defer os.Unsetenv(PWSH_LINE)
+
+	r, err := NewRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := (Request{Shell: Pwsh, Line: `tester "a b`, Point: 11}); r != x {
+		t.Errorf("Invalid request %v vs %v", r, x)
+	}
+	args, inword, err := PwshArgs()
+	if err != nil || !inword || !EqStrings(args, []string{"tester", "a b"}) {
+		t.Errorf("Invalid PowerShell args (%v,%v,%v)", args, inword, err)
+	}
+}
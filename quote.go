@@ -0,0 +1,60 @@
+package compgen
+
+import (
+	"bytes"
+	"strings"
+)
+
+/*
+this file contains the candidates escaping, so that values with spaces or special characters are inserted as typed.
+*/
+
+// bashSpecials are the characters that must be escaped in a bash word, outside of quotes.
+//
+// '~' is not escaped, so that paths like "~/dir" are still expanded.
+const bashSpecials = " \t\n'\"\\$`|&;()<>*?[]{}#!"
+
+// Escape returns the candidate value, escaped with the bash quoting rules for the 'quote' style
+// of the word being completed (see Arg.Quote):
+//
+//	'\''  within single quotes, single quotes are escaped as '\''
+//	'"'   within double quotes, the characters "\$` are escaped with a backslash
+//	0     outside of quotes (or '\\'), all special characters (like spaces) are escaped with a backslash
+//
+// The word is replaced from its open quote, if any (bash does not replace the quote itself):
+// the quote is closed, unless the value is a directory (to be completed further).
+//
+// Raw candidates are never escaped.
+func Escape(c Candidate, quote rune) string {
+	v := c.Value
+	switch {
+	case c.Raw:
+		return v
+	case quote == '\'':
+		// a single quote cannot be escaped within single quotes: close, escape, and open again
+		v = strings.Replace(v, `'`, `'\''`, -1)
+	case quote == '"':
+		v = escape(v, "\"\\$`")
+	default:
+		return escape(v, bashSpecials)
+	}
+	if c.Kind != KindDirectory {
+		v += string(quote)
+	}
+	return v
+}
+
+// escape prefixes the 'specials' characters of 'v' with a backslash
+func escape(v, specials string) string {
+	if !strings.ContainsAny(v, specials) {
+		return v
+	}
+	var b bytes.Buffer
+	for _, r := range v {
+		if strings.ContainsRune(specials, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package compgen
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	for _, c := range []struct {
+		c     Candidate
+		quote rune
+		x     string
+	}{
+		{Candidate{Value: "my file.txt"}, 0, `my\ file.txt`},
+		{Candidate{Value: "~/a&b"}, 0, `~/a\&b`},
+		{Candidate{Value: `"my file.txt"`, Raw: true}, 0, `"my file.txt"`},
+		{Candidate{Value: `my\ file.txt`, Raw: true}, '"', `my\ file.txt`},
+		{Candidate{Value: "my file.txt"}, '"', `my file.txt"`},
+		{Candidate{Value: `a"$b`}, '"', `a\"\$b"`},
+		{Candidate{Value: "it's"}, '\'', `it'\''s'`},
+		{Candidate{Value: "my $file"}, '\\', `my\ \$file`},
+		{Candidate{Value: "my dir/", Kind: KindDirectory}, '"', `my dir/`},
+		{Candidate{Value: "my dir/", Kind: KindDirectory}, '\\', `my\ dir/`},
+	} {
+		if v := Escape(c.c, c.quote); v != c.x {
+			t.Errorf("Invalid escape of %q in %q: %q vs %q", c.c.Value, c.quote, v, c.x)
+		}
+	}
+}
+
+func TestCompleteQuoted(t *testing.T) {
+	fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+	fs.String("name", "", "to set a name")
+	term := NewTerminator(fs)
+	term.Flag("name", ValueGen([]string{"toto", "my name"}))
+	term.Arg(0, ValueGen([]string{"my file.txt", "my files", "other", "pre-x"}))
+
+	for line, x := range map[string]string{
+		`cmd "my fi`:  "my file.txt\"\nmy files\"\n",
+		`cmd 'my fi`:  "my file.txt'\nmy files'\n",
+		`cmd my\ fi`:  "my\\ file.txt\nmy\\ files\n",
+		`cmd "my fil`: "my file.txt\"\nmy files\"\n",
+		`cmd ot`:      "other\n",
+		`cmd my`:      "my\\ file.txt\nmy\\ files\n",
+		// bash only replaces the text after the open quote
+		`cmd -name="t`:  "toto\"\n",
+		`cmd -name='my`: "my name'\n",
+		`cmd -name=my`:  "my\\ name\n",
+		`cmd pre"-`:     "-x\"\n",
+		`cmd "my "'fi`:  "file.txt'\nfiles'\n",
+	} {
+		var out bytes.Buffer
+		if err := term.Complete(&out, Request{Shell: Bash, Line: line, Point: len(line)}); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != x {
+			t.Errorf("Invalid output for %q: %q vs %q", line, out.String(), x)
+		}
+	}
+}
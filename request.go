@@ -0,0 +1,95 @@
+package compgen
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+this file contains the completion Request, to complete without reading the process env and args.
+*/
+
+// Request is a completion request: the command line and the cursor position, as passed by a shell.
+//
+// Line and Point depend on the Shell completion protocol:
+//
+//	Bash  $COMP_LINE and $COMP_POINT, a byte offset
+//	Zsh   the newline separated $words and $CURRENT, the one-indexed word under the cursor
+//	Fish  the `commandline -cp` output, Point is ignored: the line stops at the cursor
+//	Pwsh  the command AST text and the cursor position, a character offset
+//
+// A Request can be made by hand, to complete from a long running process or a test:
+//
+//	err := t.Complete(w, Request{Shell: Bash, Line: "cmd -na", Point: 7})
+type Request struct {
+	Shell Shell  // the completion protocol, and output format
+	Line  string // the command line
+	Point int    // the cursor position in Line
+}
+
+// NewRequest reads the Request of the current execution, from the env and the args (see DetectShell).
+//
+// The Request Shell is NoShell if not in completion mode.
+func NewRequest() (r Request, err error) {
+	r.Shell = DetectShell()
+	switch r.Shell {
+	case Bash:
+		r.Line = os.Getenv(COMP_LINE)
+		r.Point, err = strconv.Atoi(os.Getenv(COMP_POINT))
+	case Zsh:
+		r.Line = os.Getenv(ZSH_WORDS)
+		r.Point, err = strconv.Atoi(os.Getenv(ZSH_CURRENT))
+	case Fish:
+		// `commandline -cp` output is split on newlines by fish
+		r.Line = strings.Join(os.Args[2:], "\n")
+		r.Point = len(r.Line)
+	case Pwsh:
+		if len(os.Args) < 3 {
+			return r, ErrNotPwsh
+		}
+		r.Point, err = strconv.Atoi(os.Args[2])
+		r.Line = os.Getenv(PWSH_LINE)
+	}
+	return
+}
+
+// Args returns the same values as Args does for bash, whatever the Request Shell.
+func (r Request) Args() (args []string, inword bool, err error) {
+	switch r.Shell {
+	case Bash:
+		return ParseLine(r.Line, r.Point)
+	case Zsh:
+		return zshArgs(strings.Split(r.Line, "\n"), r.Point)
+	case Fish:
+		return ParseLine(r.Line, len(r.Line))
+	case Pwsh:
+		return pwshArgs(r.Line, r.Point)
+	}
+	return nil, false, ErrUnknownShell
+}
+
+// word returns the word being completed, with its quoting style (see Arg.Quote), or a zero Arg.
+//
+// Only bash needs quoted candidates, other shells quote them.
+func (r Request) word() Arg {
+	if r.Shell != Bash {
+		return Arg{}
+	}
+	args, inword, err := parseLine(r.Line, r.Point)
+	if err != nil || !inword {
+		return Arg{}
+	}
+	return args[len(args)-1]
+}
+
+// redirect returns true if the word being completed is a redirection target (like `cmd > out`), to complete files.
+//
+// Other shells complete redirections by themselves.
+func (r Request) redirect() bool {
+	if r.Shell != Bash {
+		return false
+	}
+	args, inword, err := parseLine(r.Line, r.Point)
+	return err == nil && redirection(args, inword)
+}
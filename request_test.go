@@ -0,0 +1,89 @@
+package compgen
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestRequestArgs(t *testing.T) {
+	CheckRequest(t, Request{Shell: Bash, Line: "cmd -name to", Point: 12}, true, "cmd", "-name", "to")
+	CheckRequest(t, Request{Shell: Bash, Line: "cmd -name to", Point: 10}, false, "cmd", "-name")
+	CheckRequest(t, Request{Shell: Zsh, Line: "cmd\n-name\nto", Point: 3}, true, "cmd", "-name", "to")
+	CheckRequest(t, Request{Shell: Zsh, Line: "cmd\n-name\n", Point: 3}, false, "cmd", "-name")
+	CheckRequest(t, Request{Shell: Fish, Line: "cmd -name to"}, true, "cmd", "-name", "to")
+	CheckRequest(t, Request{Shell: Pwsh, Line: "cmd -name", Point: 10}, false, "cmd", "-name")
+
+	if _, _, err := (Request{Line: "cmd"}).Args(); err != ErrUnknownShell {
+		t.Errorf("Invalid error for NoShell: %v", err)
+	}
+}
+
+func CheckRequest(t *testing.T, r Request, xinword bool, xargs ...string) {
+	args, inword, err := r.Args()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inword != xinword || !EqStrings(args, xargs) {
+		t.Errorf("Invalid args for %v: (%v,%v) vs (%v,%v)", r, args, inword, xargs, xinword)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	os.Setenv(COMP_LINE, "cmd -na")
+	os.Setenv(COMP_POINT, "7")
+	defer os.Unsetenv(COMP_LINE)
+	defer os.Unsetenv(COMP_POINT)
+
+	r, err := NewRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := (Request{Shell: Bash, Line: "cmd -na", Point: 7}); r != x {
+		t.Errorf("Invalid request %v vs %v", r, x)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	for _, c := range []struct {
+		r Request
+		x string
+	}{
+		{Request{Shell: Bash, Line: "cmd -name=t", Point: 11}, "toto\ntata\ntiti\n"},
+		{Request{Shell: Zsh, Line: "cmd\n-na", Point: 2}, "\t-name:to set a name\n"},
+		{Request{Shell: Fish, Line: "cmd -name ta"}, "tata\n"},
+		{Request{Shell: Pwsh, Line: "cmd -y", Point: 6}, "-yes\t-yes\tParameterName\tto say yes\n"},
+		{Request{Shell: Bash, Line: "cat x | cmd -name=t", Point: 19}, "toto\ntata\ntiti\n"},
+		{Request{Shell: Bash, Line: "cmd -yes > request_t", Point: 20}, "request_test.go\n"},
+		{Request{Shell: Bash, Line: "cmd 2>request_t", Point: 15}, "request_test.go\n"},
+		{Request{Shell: Bash, Line: "FOO=1 sudo -E cmd -name=t", Point: 25}, "toto\ntata\ntiti\n"},
+	} {
+		var out bytes.Buffer
+		if err := newTerminator().Complete(&out, c.r); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != c.x {
+			t.Errorf("Invalid output for %v: %q vs %q", c.r, out.String(), c.x)
+		}
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+var errWrite = errors.New("write error")
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errWrite }
+
+func TestCompleteErrors(t *testing.T) {
+	if err := newTerminator().Complete(&bytes.Buffer{}, Request{Shell: Bash, Line: "cmd", Point: 10}); err != ErrInvalidPoint {
+		t.Errorf("Invalid error for an invalid point: %v", err)
+	}
+	if err := newTerminator().Complete(&bytes.Buffer{}, Request{Shell: Bash, Line: "cmd -x ", Point: 7}); err == nil {
+		t.Errorf("Invalid error for an unknown flag: %v", err)
+	}
+	if err := newTerminator().Complete(failingWriter{}, Request{Shell: Bash, Line: "cmd -", Point: 5}); err != errWrite {
+		t.Errorf("Invalid error for a failing writer: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package compgen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+this file contains the registration scripts generation.
+*/
+
+const (
+	COMPLETION = "completion" // first argument of the script generation command
+)
+
+var (
+	ErrUnknownShell = errors.New("Unknown shell")
+)
+
+// ParseShell returns the Shell for a shell name, like "bash", "zsh", "fish", "pwsh" or a path to a shell like $SHELL.
+func ParseShell(name string) (Shell, error) {
+	switch strings.ToLower(filepath.Base(name)) {
+	case "bash":
+		return Bash, nil
+	case "zsh":
+		return Zsh, nil
+	case "fish":
+		return Fish, nil
+	case "pwsh", "powershell":
+		return Pwsh, nil
+	}
+	return NoShell, ErrUnknownShell
+}
+
+// ScriptPath returns the conventional system wide path to install the script for 'cmd' in 'shell'
+// or "" if there is no such path.
+func ScriptPath(shell Shell, cmd string) string {
+	switch shell {
+	case Bash:
+		return "/etc/bash_completion.d/" + cmd
+	case Zsh:
+		return "/usr/local/share/zsh/site-functions/_" + cmd
+	case Fish:
+		return "/usr/local/share/fish/vendor_completions.d/" + cmd + ".fish"
+	}
+	return ""
+}
+
+// Script writes the script that registers 'cmd' as self completing in 'shell'.
+//
+// The script starts with comments explaining where to install it.
+func Script(w io.Writer, shell Shell, cmd string) error {
+	switch shell {
+	case Bash:
+		return BashScript(w, cmd)
+	case Zsh:
+		return ZshScript(w, cmd)
+	case Fish:
+		return FishScript(w, cmd)
+	case Pwsh:
+		return PwshScript(w, cmd)
+	}
+	return ErrUnknownShell
+}
+
+// Completion implements the `cmd completion <shell>` entry point.
+//
+// If args (usually os.Args[1:]) are "completion <shell>", this function writes the registration script
+// for 'shell' to the stdout and *exit*. The shell defaults to $SHELL.
+//
+//	cmd completion bash > /etc/bash_completion.d/cmd
+//
+// Otherwise this function simply returns.
+//
+// Completion reads the process env and args, and writes to the stdout: see WriteCompletion for the underlying function.
+func Completion(args []string) {
+	done, err := WriteCompletion(os.Stdout, args, filepath.Base(os.Args[0]))
+	if !done {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s (expecting bash, zsh, fish or pwsh)\n", err)
+		os.Exit(-1)
+	}
+	os.Exit(0)
+}
+
+// WriteCompletion writes the registration script for 'cmd' to 'w', if args are "completion <shell>" (see Completion).
+//
+// 'done' is false if args are not a completion command, nothing is written then.
+func WriteCompletion(w io.Writer, args []string, cmd string) (done bool, err error) {
+	if len(args) == 0 || args[0] != COMPLETION || len(args) > 2 {
+		return false, nil
+	}
+	name := os.Getenv("SHELL")
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	shell, err := ParseShell(name)
+	if err != nil {
+		return true, err
+	}
+	return true, Script(w, shell, cmd)
+}
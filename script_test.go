@@ -0,0 +1,76 @@
+package compgen
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseShell(t *testing.T) {
+	for name, x := range map[string]Shell{
+		"bash":          Bash,
+		"/bin/zsh":      Zsh,
+		"fish":          Fish,
+		"pwsh":          Pwsh,
+		"PowerShell":    Pwsh,
+		"/usr/bin/tcsh": NoShell,
+	} {
+		s, _ := ParseShell(name)
+		if s != x {
+			t.Errorf("Invalid shell for %q: %v vs %v", name, s, x)
+		}
+	}
+}
+
+func TestScript(t *testing.T) {
+	for shell, x := range map[Shell]string{
+		Bash: "complete -F _tester_complete tester",
+		Zsh:  "compdef _tester tester",
+		Fish: "complete -c tester",
+		Pwsh: "Register-ArgumentCompleter",
+	} {
+		var buf bytes.Buffer
+		if err := Script(&buf, shell, "tester"); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), x) {
+			t.Errorf("%v script does not contain %q", shell, x)
+		}
+		if p := ScriptPath(shell, "tester"); p != "" && !strings.Contains(buf.String(), p) {
+			t.Errorf("%v script does not contain the install path %q", shell, p)
+		}
+	}
+
+	if err := Script(new(bytes.Buffer), NoShell, "tester"); err != ErrUnknownShell {
+		t.Errorf("Invalid error %v vs %v", err, ErrUnknownShell)
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	defer os.Setenv("SHELL", os.Getenv("SHELL"))
+	os.Setenv("SHELL", "/bin/zsh")
+
+	for _, c := range []struct {
+		args []string
+		done bool
+		err  error
+		x    string
+	}{
+		{[]string{"completion", "fish"}, true, nil, "complete -c tester"},
+		{[]string{"completion"}, true, nil, "compdef _tester tester"},
+		{[]string{"completion", "tcsh"}, true, ErrUnknownShell, ""},
+		{[]string{"completion", "bash", "more"}, false, nil, ""},
+		{[]string{"run", "bash"}, false, nil, ""},
+		{nil, false, nil, ""},
+	} {
+		var buf bytes.Buffer
+		done, err := WriteCompletion(&buf, c.args, "tester")
+		if done != c.done || err != c.err {
+			t.Errorf("Invalid completion for %v: (%v,%v) vs (%v,%v)", c.args, done, err, c.done, c.err)
+		}
+		if !strings.Contains(buf.String(), c.x) || c.x == "" && buf.Len() > 0 {
+			t.Errorf("Invalid completion script for %v: %q does not contain %q", c.args, buf.String(), c.x)
+		}
+	}
+}
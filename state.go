@@ -0,0 +1,46 @@
+package compgen
+
+import (
+	"flag"
+)
+
+/*
+this file contains the completion State, for generators that depend on the rest of the command line.
+*/
+
+// State is the completion state, as parsed by the Terminator.
+//
+// A `-branch` generator can read the `-repo` value already set:
+//
+//	func(s State) []Candidate {
+//		repo := s.FlagSet.Lookup("repo").Value.String()
+//		...
+//	}
+type State struct {
+	Args     []string      // the full args, up to the cursor. Args[0] is the command
+	FlagSet  *flag.FlagSet // a parsed copy of the FlagSet: flags already set can be read with Lookup or Visit, as text. nil for other Flags
+	Flags    Flags         // a parsed copy of the Flags, for any flag package (see NewFlagsTerminator)
+	Case     CompCase      // the completion case
+	Flag     string        // the flag name (without dashes), when completing a flag value
+	Position int           // the zero-indexed argument position, when completing args. -1 otherwise
+	Prefix   string        // the word being completed
+	Inword   bool          // true if the cursor is within a word
+	Matcher  Matcher       // the Terminator Matcher, to filter out candidates (see Matcher.Filter)
+}
+
+// StateGen is a function to generate Candidate from the completion State.
+//
+// It is the context aware version of a CandidateGen.
+type StateGen func(s State) []Candidate
+
+// State adapts a CandidateGen into a StateGen: only the prefix is used.
+func (gen CandidateGen) State() StateGen {
+	return func(s State) []Candidate {
+		return gen(s.Prefix)
+	}
+}
+
+// State adapts a Compgen into a StateGen: only the prefix is used.
+func (gen Compgen) State() StateGen {
+	return gen.Candidates().State()
+}
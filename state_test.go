@@ -0,0 +1,97 @@
+package compgen
+
+import (
+	"flag"
+	"sync"
+	"testing"
+)
+
+// repoTerminator returns a Terminator whose -branch values depend on -repo
+func repoTerminator(states *[]State) *Terminator {
+	fs := flag.NewFlagSet("t", flag.ContinueOnError)
+	fs.String("repo", "origin", "the repository")
+	fs.String("branch", "", "the branch")
+
+	branches := map[string][]string{
+		"origin":   {"master", "dev"},
+		"upstream": {"main"},
+	}
+	record := func(s State) []Candidate {
+		*states = append(*states, s)
+		return nil
+	}
+
+	term := NewTerminator(fs)
+	term.FlagState("branch", func(s State) []Candidate {
+		repo := s.FlagSet.Lookup("repo").Value.String()
+		return ValueGen(branches[repo]).Candidates()(s.Prefix)
+	})
+	term.ArgState(0, record)
+	term.ArgsState(record)
+	return term
+}
+
+func TestFlagState(t *testing.T) {
+	CheckState(t, []string{"cmd", "-branch"}, false, "master", "dev")
+	CheckState(t, []string{"cmd", "-repo", "upstream", "-branch"}, false, "main")
+	CheckState(t, []string{"cmd", "-repo", "upstream", "-branch", "m"}, true, "main")
+}
+
+func CheckState(t *testing.T, args []string, inword bool, x ...string) {
+	comp, err := repoTerminator(new([]State)).Compgen(args, inword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqStrings(comp, x) {
+		t.Errorf("Invalid completion for %v %v: %v vs %v", args, inword, comp, x)
+	}
+}
+
+func TestConcurrentStates(t *testing.T) {
+	// the same Terminator completes concurrently, its FlagSet is never parsed
+	term := repoTerminator(new([]State))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		for _, c := range []struct {
+			args []string
+			x    []string
+		}{
+			{[]string{"cmd", "-branch"}, []string{"master", "dev"}},
+			{[]string{"cmd", "-repo", "upstream", "-branch"}, []string{"main"}},
+		} {
+			wg.Add(1)
+			go func(args, x []string) {
+				defer wg.Done()
+				if comp, err := term.Compgen(args, false); err != nil || !EqStrings(comp, x) {
+					t.Errorf("Invalid completion for %v: %v vs %v (%v)", args, comp, x, err)
+				}
+			}(c.args, c.x)
+		}
+	}
+	wg.Wait()
+	if fs := term.FlagSet(); fs.Parsed() || fs.Lookup("repo").Value.String() != "origin" {
+		t.Errorf("The FlagSet has been parsed: -repo %v", fs.Lookup("repo").Value)
+	}
+}
+
+func TestArgsState(t *testing.T) {
+	var states []State
+	repoTerminator(&states).Compgen([]string{"cmd", "-repo", "upstream", "to"}, true)
+	repoTerminator(&states).Compgen([]string{"cmd", "toto", "ta"}, true)
+
+	if len(states) != 2 {
+		t.Fatalf("Invalid number of StateGen calls %v", len(states))
+	}
+	for i, x := range []State{
+		{Case: CompArgs, Position: 0, Prefix: "to", Inword: true},
+		{Case: CompArgs, Position: 1, Prefix: "ta", Inword: true},
+	} {
+		s := states[i]
+		if s.Case != x.Case || s.Position != x.Position || s.Prefix != x.Prefix || s.Inword != x.Inword {
+			t.Errorf("Invalid State %+v vs %+v", s, x)
+		}
+	}
+	if repo := states[0].FlagSet.Lookup("repo").Value.String(); repo != "upstream" {
+		t.Errorf("Invalid parsed -repo %q", repo)
+	}
+}
@@ -0,0 +1,265 @@
+package compgen
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+this file contains the struct tags driven Command builder, for CLIs that define their options as structs.
+*/
+
+var (
+	ErrNotStruct = errors.New("Not a pointer to a struct")
+)
+
+// NewStructCommand creates a new Command from a tagged struct: the FlagSet is bound to the struct fields,
+// and the Terminator is configured from the tags.
+//
+//	type Options struct {
+//		Level   string         `flag:"level" usage:"the log level" enum:"debug,info,warn"`
+//		Config  string         `flag:"config" usage:"the config file" complete:"file:*.yaml,*.yml"`
+//		Verbose bool           `flag:"v" usage:"verbose output"`
+//		Target  string         `arg:"0" complete:"hostname"`
+//		Files   []string       `arg:"*" complete:"file"`
+//		Commit  *CommitOptions `cmd:"commit" alias:"ci" usage:"Record changes to the repository"`
+//	}
+//
+//	opts := &Options{Level: "info"}
+//	root, err := NewStructCommand("cmd", "does things", opts)
+//	root.Terminate()
+//	root.FlagSet().Parse(os.Args[1:])
+//	err = ParseStructArgs(opts, root.FlagSet().Args())
+//
+// The tags are:
+//
+//	flag      the flag name. The field current value is the flag default value.
+//	usage     the flag or subcommand usage.
+//	arg       the zero-indexed argument position, or "*" for all other args.
+//	enum      the comma separated values, see ValueGen.
+//	complete  "file" (with optional comma separated patterns, like "file:*.go"), "dir", or a compgen action, see ActionGen.
+//	cmd       the subcommand name, for a struct (or pointer to struct) field, that is built the same way.
+//	alias     the comma separated subcommand aliases.
+//
+// Flag fields can be a string, bool, int, int64, uint, uint64, float64, time.Duration or implement flag.Value.
+// Arg fields are a string, or a []string for "*": args are not parsed by the FlagSet, see ParseStructArgs.
+// Embedded structs without tags are built into the same Command, other fields without tags are ignored.
+func NewStructCommand(name, usage string, v interface{}) (*Command, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotStruct
+	}
+	c := NewCommand(name, usage, flag.NewFlagSet(name, flag.ContinueOnError))
+	if err := buildStruct(c, val.Elem()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ParseStructArgs fills the `arg` fields of a tagged struct (see NewStructCommand) with the non-flag args:
+// each position with its arg, and "*" with the args at positions without a field.
+//
+// Only the struct own fields (and those of embedded structs) are filled, subcommand fields need their own args:
+//
+//	err = ParseStructArgs(opts.Commit, commit.FlagSet().Args())
+func ParseStructArgs(v interface{}, args []string) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return ErrNotStruct
+	}
+	used := make(map[int]bool)
+	var rest reflect.Value // the "*" field, if any
+	if err := fillArgs(val.Elem(), args, used, &rest); err != nil {
+		return err
+	}
+	if rest.IsValid() {
+		others := []string{}
+		for i, a := range args {
+			if !used[i] {
+				others = append(others, a)
+			}
+		}
+		rest.Set(reflect.ValueOf(others))
+	}
+	return nil
+}
+
+// fillArgs sets the positional `arg` fields, and records the "*" field in 'rest'.
+func fillArgs(v reflect.Value, args []string, used map[int]bool, rest *reflect.Value) error {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field, fv := typ.Field(i), v.Field(i)
+		if field.PkgPath != "" && !field.Anonymous { // unexported
+			continue
+		}
+
+		switch tag := field.Tag; {
+		case tag.Get("arg") != "":
+			if err := checkArg(field, fv); err != nil {
+				return err
+			}
+			if pos := tag.Get("arg"); pos == "*" {
+				*rest = fv
+			} else if n, _ := strconv.Atoi(pos); n < len(args) {
+				fv.SetString(args[n])
+				used[n] = true
+			}
+		case tag.Get("flag") == "" && tag.Get("cmd") == "" && field.Anonymous && fv.Kind() == reflect.Struct:
+			if err := fillArgs(fv, args, used, rest); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkArg returns an error if an `arg` field cannot be filled: it must be a string, or a []string for "*".
+func checkArg(field reflect.StructField, v reflect.Value) error {
+	pos := field.Tag.Get("arg")
+	if pos != "*" {
+		if n, err := strconv.Atoi(pos); err != nil || n < 0 {
+			return fmt.Errorf("Invalid position %q for arg %s", pos, field.Name)
+		}
+	}
+	switch {
+	case !v.CanSet():
+		return fmt.Errorf("Unexported field %s for arg %s", field.Name, pos)
+	case pos == "*" && v.Type() != reflect.TypeOf([]string(nil)):
+		return fmt.Errorf("Unsupported type %s for arg %s, expecting []string", field.Type, pos)
+	case pos != "*" && v.Kind() != reflect.String:
+		return fmt.Errorf("Unsupported type %s for arg %s, expecting string", field.Type, pos)
+	}
+	return nil
+}
+
+// buildStruct binds the struct fields to the Command FlagSet, and configures the Command.
+func buildStruct(c *Command, v reflect.Value) error {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field, fv := typ.Field(i), v.Field(i)
+		if field.PkgPath != "" && !field.Anonymous { // unexported
+			continue
+		}
+
+		var err error
+		switch tag := field.Tag; {
+		case tag.Get("flag") != "":
+			err = structFlag(c, field, fv)
+		case tag.Get("arg") != "":
+			err = structArg(c, field, fv)
+		case tag.Get("cmd") != "":
+			err = structCmd(c, field, fv)
+		case field.Anonymous && fv.Kind() == reflect.Struct:
+			err = buildStruct(c, fv)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// structFlag defines the flag of a `flag:"name"` field
+func structFlag(c *Command, field reflect.StructField, v reflect.Value) error {
+	name, usage := field.Tag.Get("flag"), field.Tag.Get("usage")
+	if !v.CanAddr() || !v.Addr().CanInterface() {
+		return fmt.Errorf("Unexported field %s for flag -%s", field.Name, name)
+	}
+
+	fs := c.FlagSet()
+	switch p := v.Addr().Interface().(type) {
+	case flag.Value:
+		fs.Var(p, name, usage)
+	case *string:
+		fs.StringVar(p, name, *p, usage)
+	case *bool:
+		fs.BoolVar(p, name, *p, usage)
+	case *int:
+		fs.IntVar(p, name, *p, usage)
+	case *int64:
+		fs.Int64Var(p, name, *p, usage)
+	case *uint:
+		fs.UintVar(p, name, *p, usage)
+	case *uint64:
+		fs.Uint64Var(p, name, *p, usage)
+	case *float64:
+		fs.Float64Var(p, name, *p, usage)
+	case *time.Duration:
+		fs.DurationVar(p, name, *p, usage)
+	default:
+		return fmt.Errorf("Unsupported type %s for flag -%s", field.Type, name)
+	}
+
+	if gen := structGen(field); gen != nil {
+		c.FlagCandidates(name, gen)
+	}
+	return nil
+}
+
+// structArg configures the generator of an `arg:"position"` field
+func structArg(c *Command, field reflect.StructField, v reflect.Value) error {
+	if err := checkArg(field, v); err != nil {
+		return err
+	}
+	gen := structGen(field)
+	if gen == nil {
+		return nil // nothing to complete
+	}
+	if pos := field.Tag.Get("arg"); pos == "*" {
+		c.ArgsState(gen.State())
+	} else {
+		n, _ := strconv.Atoi(pos)
+		c.ArgCandidates(n, gen)
+	}
+	return nil
+}
+
+// structCmd builds the subcommand of a `cmd:"name"` field
+func structCmd(c *Command, field reflect.StructField, v reflect.Value) error {
+	name := field.Tag.Get("cmd")
+	if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("Unsupported type %s for command %s", field.Type, name)
+	}
+
+	sub := c.Sub(name, field.Tag.Get("usage"), flag.NewFlagSet(name, flag.ContinueOnError))
+	if aliases := field.Tag.Get("alias"); aliases != "" {
+		sub.Alias(strings.Split(aliases, ",")...)
+	}
+	return buildStruct(sub, v)
+}
+
+// structGen returns the CandidateGen for the `enum` or `complete` tags of a field, or nil.
+func structGen(field reflect.StructField) CandidateGen {
+	if enum := field.Tag.Get("enum"); enum != "" {
+		return ValueGen(strings.Split(enum, ",")).Candidates()
+	}
+
+	complete := field.Tag.Get("complete")
+	action, patterns := complete, ""
+	if i := strings.Index(complete, ":"); i >= 0 {
+		action, patterns = complete[:i], complete[i+1:]
+	}
+	switch action {
+	case "":
+		return nil
+	case "file":
+		if patterns == "" {
+			return FileGen()
+		}
+		return FileGen(strings.Split(patterns, ",")...)
+	case "dir":
+		return DirGen()
+	}
+	return ActionGen(action).Candidates()
+}
@@ -0,0 +1,144 @@
+package compgen
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+type commonOptions struct {
+	Verbose bool `flag:"v" usage:"verbose output"`
+}
+
+type commitOptions struct {
+	Message string   `flag:"m" usage:"the commit message"`
+	Files   []string `arg:"*" complete:"file:*.go"`
+}
+
+type remoteOptions struct {
+	Add struct {
+		Name string `arg:"0" enum:"origin,upstream"`
+	} `cmd:"add" usage:"Add a remote"`
+}
+
+type structOptions struct {
+	commonOptions
+	Level   string         `flag:"level" usage:"the log level" enum:"debug,info,warn"`
+	Config  string         `flag:"config" usage:"the config file" complete:"file:*.yaml"`
+	Timeout time.Duration  `flag:"timeout" usage:"the timeout"`
+	Force   forceValue     `flag:"force" usage:"force it"`
+	Commit  *commitOptions `cmd:"commit" alias:"ci" usage:"Record changes to the repository"`
+	Remote  remoteOptions  `cmd:"remote" usage:"Manage set of tracked repositories"`
+	ignored string
+}
+
+func TestStructCommand(t *testing.T) {
+	root := tempTree(t)
+	defer os.RemoveAll(root)
+
+	for _, c := range []struct {
+		args   []string
+		inword bool
+		x      []string
+	}{
+		{[]string{"cmd", "-"}, true, []string{"-config", "-force", "-level", "-timeout", "-v"}},
+		{[]string{"cmd", "-level"}, false, []string{"debug", "info", "warn"}},
+		{[]string{"cmd", "-timeout"}, false, []string{"1s"}},
+		{[]string{"cmd", "-config", root + "con"}, true, []string{root + "conf/", root + "config.yaml"}},
+		{[]string{"cmd", "-force", "c"}, true, []string{"commit"}},
+		{[]string{"cmd", "ci", "-"}, true, []string{"-m"}},
+		{[]string{"cmd", "commit", root + "conf/"}, true, []string{}},
+		{[]string{"cmd", "remote", "add", "o"}, true, []string{"origin"}},
+	} {
+		opts := &structOptions{Timeout: time.Second}
+		cmd, err := NewStructCommand("cmd", "does things", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		comp, err := cmd.Compgen(c.args, c.inword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !EqStrings(comp, c.x) {
+			t.Errorf("Invalid completion for %v %v: %v vs %v", c.args, c.inword, comp, c.x)
+		}
+	}
+}
+
+func TestStructCommandParse(t *testing.T) {
+	opts := &structOptions{}
+	cmd, err := NewStructCommand("cmd", "does things", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.FlagSet().Parse([]string{"-v", "-level", "warn", "-force"}); err != nil {
+		t.Fatal(err)
+	}
+	if !opts.Verbose || opts.Level != "warn" || !bool(opts.Force) {
+		t.Errorf("Invalid parsed options %+v", opts)
+	}
+	if opts.Commit == nil {
+		t.Errorf("Invalid nil subcommand options")
+	}
+}
+
+func TestStructCommandErrors(t *testing.T) {
+	if _, err := NewStructCommand("cmd", "", structOptions{}); err != ErrNotStruct {
+		t.Errorf("Invalid error for a struct: %v", err)
+	}
+	var unsupported struct {
+		Values []string `flag:"values"`
+	}
+	if _, err := NewStructCommand("cmd", "", &unsupported); err == nil {
+		t.Errorf("Invalid error for an unsupported type")
+	}
+	var position struct {
+		Name string `arg:"first" enum:"a,b"`
+	}
+	if _, err := NewStructCommand("cmd", "", &position); err == nil {
+		t.Errorf("Invalid error for an invalid position")
+	}
+	var argType struct {
+		Count int `arg:"0"`
+	}
+	if c, err := NewStructCommand("cmd", "", &argType); err == nil || c != nil {
+		t.Errorf("Invalid error for an unsupported arg type: %v %v", c, err)
+	}
+	var argsType struct {
+		Files string `arg:"*"`
+	}
+	if _, err := NewStructCommand("cmd", "", &argsType); err == nil {
+		t.Errorf("Invalid error for an unsupported args type")
+	}
+}
+
+func TestParseStructArgs(t *testing.T) {
+	var opts struct {
+		commonArgs
+		Files []string `arg:"*"`
+		Mode  string   `arg:"2"`
+	}
+	if err := ParseStructArgs(&opts, []string{"host", "a.go", "fast", "b.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Target != "host" || opts.Mode != "fast" || !EqStrings(opts.Files, []string{"a.go", "b.go"}) {
+		t.Errorf("Invalid parsed args %+v", opts)
+	}
+
+	// missing args are left unchanged
+	opts.Mode = "slow"
+	if err := ParseStructArgs(&opts, []string{"other"}); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Target != "other" || opts.Mode != "slow" || len(opts.Files) != 0 {
+		t.Errorf("Invalid parsed args %+v", opts)
+	}
+
+	if err := ParseStructArgs(opts, nil); err != ErrNotStruct {
+		t.Errorf("Invalid error for a struct: %v", err)
+	}
+}
+
+type commonArgs struct {
+	Target string `arg:"0" complete:"hostname"`
+}
@@ -1,12 +1,15 @@
 package compgen
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
 //Comgen is a function to generate a single kind of values
@@ -17,15 +20,22 @@ type Argsgen interface {
 	Compgen(args []string, inword bool) (comp []string, err error)
 }
 
+// CandidateArgsgen is the interface an Argsgen can also implement to generate Candidates with descriptions.
+type CandidateArgsgen interface {
+	Candidates(args []string, inword bool) (comp []Candidate, err error)
+}
+
 // Terminator is the basic object to deal with completion.
 //
 // Basically, you create one `NewTerminator` with a flag.FlagSet, you Configure it and run Terminate
 //
-// Terminate() will generate a list of suggestions for the current bash completion line
+// Terminate() will generate a list of suggestions for the current bash (zsh, fish or PowerShell) completion line
 // and write them to the stdout.
 //
 // Terminate() can be called anytime, if will do nothing if not in completion mode.
 //
+// Complete(w, request) does the same for a Request made by hand (like in a long running process), and returns errors instead of exiting.
+//
 // Caveat: bash_completion mode require a clean stdout (and stderr) so be careful to not output anything before calling Terminate.
 //
 //
@@ -36,6 +46,26 @@ type Argsgen interface {
 //
 //    Flag(name, compgen)
 //
+// Flags and positional arguments can also be mapped to a CandidateGen, to provide descriptions (see FlagCandidates and ArgCandidates).
+//
+// Generators that depend on the rest of the command line (like a `-branch` that depends on the `-repo` value)
+// can be mapped as a StateGen, they receive the parsed State (see FlagState, ArgState and ArgsState).
+//
+// Generators that can be slow (network, disk) can be mapped as a ContextGen (see FlagContext, ArgContext and ArgsContext),
+// they are cancelled when the Terminator timeout expires:
+//
+//    Timeout(duration)
+//
+// When the timeout expires, the candidates already generated are used. Generators that timed out are reported
+// in the debug output, if any (see Debug).
+//
+// Candidates are matched against the word being completed by prefix. Other strategies, like FuzzyMatch,
+// can be set, for the flag names, the default flag values and the subcommand names:
+//
+//    Matcher(matcher)
+//
+// Generators can use it too, it is in the State. Candidates are then ranked, best match first.
+//
 // When Terminator look for suggestion for an 'arg' ( like `cmd toto<TAB>`) it will try first
 // the Argsgen if not nil, then a positional Compgen
 //
@@ -50,6 +80,9 @@ type Argsgen interface {
 //    `cmd toto <TAB> titi`    1
 //
 // If there is a Compgen mapped to the actual completion position, then it is used.
+// Otherwise the varargs StateGen is used, if any:
+//
+//    ArgsState(stategen)
 //
 //
 // Recursion
@@ -58,38 +91,152 @@ type Argsgen interface {
 // It is then possible to use it recursively.
 //
 // One common usecase is to use it to implement subcommands.
-// Each subcommand has it's own "terminator" configured, and you just need to implement
-// a Argsgen to dispatch to the right subcommander.
+// Each subcommand has it's own "terminator" configured, and an Argsgen dispatch to the right subcommander.
+// Command implements this pattern, see Command.Sub.
 type Terminator struct {
-	fs        *flag.FlagSet
-	keyvalgen map[string]Compgen // ability to set a Comgen for each key val
-	arggen    map[int]Compgen    // positional Compgen
-	argsgen   Argsgen            // the compgen for varargs
+	fs         *flag.FlagSet         // the standard FlagSet, if any
+	flags      Flags                 // the flag introspection
+	keyvalgen  map[string]ContextGen // ability to set a Comgen for each key val
+	arggen     map[int]ContextGen    // positional Compgen
+	argsgen    Argsgen               // the compgen for varargs
+	varargsgen ContextGen            // the compgen for args without positional Compgen
+	equals     bool                  // complete flag names in the `-name=` form
+	timeout    time.Duration         // the completion budget, 0 for none
+	debug      io.Writer             // the debug output, if any
+	matcher    Matcher               // the matching strategy, nil to inherit it
+	wrappers   map[string][]string   // the commands that run the command, see Wrappers
 }
 
 //NewTerminator creates a new Terminator
 func NewTerminator(fs *flag.FlagSet) (t *Terminator) {
-	t = new(Terminator)
+	t = NewFlagsTerminator(StdFlags(fs))
 	t.fs = fs
 	return t
 }
 
+// NewFlagsTerminator creates a new Terminator for any flag package (see Flags)
+func NewFlagsTerminator(flags Flags) (t *Terminator) {
+	t = new(Terminator)
+	t.flags = flags
+	t.wrappers = DefaultWrappers()
+	return t
+}
+
+// FlagSet returns the standard FlagSet the Terminator completes, or nil (see NewFlagsTerminator)
+func (t *Terminator) FlagSet() *flag.FlagSet { return t.fs }
+
+// Flags returns the Flags the Terminator completes
+func (t *Terminator) Flags() Flags { return t.flags }
+
 //Flag maps a Compgen to a given flag by name
 func (t *Terminator) Flag(name string, gen Compgen) {
+	t.FlagCandidates(name, gen.Candidates())
+}
+
+// FlagCandidates maps a CandidateGen to a given flag by name
+func (t *Terminator) FlagCandidates(name string, gen CandidateGen) {
+	t.FlagState(name, gen.State())
+}
+
+// FlagState maps a StateGen to a given flag by name
+func (t *Terminator) FlagState(name string, gen StateGen) {
+	t.FlagContext(name, gen.Context())
+}
+
+// FlagContext maps a ContextGen to a given flag by name
+func (t *Terminator) FlagContext(name string, gen ContextGen) {
 	if t.keyvalgen == nil {
-		t.keyvalgen = make(map[string]Compgen)
+		t.keyvalgen = make(map[string]ContextGen)
 	}
 	t.keyvalgen[name] = gen
 }
 
 //Arg maps a Compgen to a positional argument
 func (t *Terminator) Arg(pos int, gen Compgen) {
+	t.ArgCandidates(pos, gen.Candidates())
+}
+
+// ArgCandidates maps a CandidateGen to a positional argument
+func (t *Terminator) ArgCandidates(pos int, gen CandidateGen) {
+	t.ArgState(pos, gen.State())
+}
+
+// ArgState maps a StateGen to a positional argument
+func (t *Terminator) ArgState(pos int, gen StateGen) {
+	t.ArgContext(pos, gen.Context())
+}
+
+// ArgContext maps a ContextGen to a positional argument
+func (t *Terminator) ArgContext(pos int, gen ContextGen) {
 	if t.arggen == nil {
-		t.arggen = make(map[int]Compgen)
+		t.arggen = make(map[int]ContextGen)
 	}
 	t.arggen[pos] = gen
 }
 
+// ArgsState set the StateGen to be used for args without positional Compgen
+func (t *Terminator) ArgsState(gen StateGen) {
+	t.ArgsContext(gen.Context())
+}
+
+// ArgsContext set the ContextGen to be used for args without positional Compgen
+func (t *Terminator) ArgsContext(gen ContextGen) {
+	t.varargsgen = gen
+}
+
+// Timeout sets the completion budget: when it expires, generators are cancelled
+// and only the candidates already generated are used. 0 means no timeout.
+//
+// There is no timeout in the process that refreshes a Cache.
+func (t *Terminator) Timeout(d time.Duration) {
+	t.timeout = d
+}
+
+// Debug sets a debug output, like a log file (the stdout and stderr are reserved to the shell).
+func (t *Terminator) Debug(w io.Writer) {
+	t.debug = w
+}
+
+// Matcher sets the matching strategy (PrefixMatch by default).
+//
+// Nested Terminators (like subcommands) inherit it, unless they set their own.
+//
+// Caveat: bash only accepts candidates that start with the word being completed, prefer zsh or fish.
+func (t *Terminator) Matcher(m Matcher) {
+	t.matcher = m
+}
+
+// Wrappers sets the commands that run another command, like `sudo -u root cmd`, mapped to their options that need a value
+// (like "-u" or "--user"). Leading env assignments (like `FOO=1`), wrappers and their options are skipped before
+// completing. It is DefaultWrappers by default, nil for none.
+//
+// Only the Terminator that completes the Request uses it (not subcommands).
+func (t *Terminator) Wrappers(wrappers map[string][]string) {
+	t.wrappers = wrappers
+}
+
+// FlagEquals sets whether flag names are completed in the `-name=` form (see FlagEqualsNameGen).
+//
+// Caveat: bash appends a space after a single suggestion, like `-name=`, when registered with a plain `complete -C cmd cmd`.
+// The script written by BashScript does not.
+func (t *Terminator) FlagEquals(enabled bool) {
+	t.equals = enabled
+}
+
+// GNU sets whether flags follow the GNU conventions (see GNUFlags): `--name` long names,
+// `-v` shorthands that can be clustered like `-xvf`, and the `--` separator. Flags can follow args, like in `cmd arg -v`.
+//
+// It only applies to Terminators of a standard flag.FlagSet (see NewTerminator).
+func (t *Terminator) GNU(enabled bool) {
+	switch {
+	case t.fs == nil:
+	case enabled:
+		t.flags = GNUFlags(t.fs)
+	default:
+		t.flags = StdFlags(t.fs)
+	}
+}
+
 // Argsgen set the interface to be used to deal with varargs
 func (t *Terminator) Argsgen(a Argsgen) {
 	t.argsgen = a
@@ -100,35 +247,85 @@ func (t *Terminator) Argsgen(a Argsgen) {
 // the current command line and *exit*
 //
 // If it is not in completion mode, then this methods simply returns
+//
+// Terminate reads the process env and args, and writes to the stdout: see Complete for the underlying method.
 func (t *Terminator) Terminate() {
-
-	if !IsCompletionMode() {
+	r, err := NewRequest()
+	if r.Shell == NoShell { // not in completion mode
 		return
 	}
-	args, inword, err := Args()
 	if err != nil {
 		os.Exit(-1)
 	}
-	pred, err := t.Compgen(args, inword)
-	if err != nil {
+	if err := t.Complete(os.Stdout, r); err != nil {
 		os.Exit(-1)
 	}
-	fmt.Println(strings.Join(pred, "\n"))
 	os.Exit(0)
 }
 
+// Complete completes the Request command line, and writes the candidates to 'w' in the Request Shell format.
+func (t *Terminator) Complete(w io.Writer, r Request) error {
+	args, inword, err := r.Args()
+	if err != nil {
+		return err
+	}
+	// leading env assignments and wrappers (like `sudo -E cmd`) are not part of the command
+	i, ok := unwrap(args, inword, t.wrappers)
+	args = args[i:]
+	inword = inword && len(args) > 1 // the command name is never in word
+	var pred []Candidate
+	switch {
+	case r.redirect(): // the command is not involved
+		_, prefix := Prefix(args, inword)
+		pred = FileGen()(prefix)
+	case !ok: // the cursor is on the command itself, or on a wrapper option: nothing to complete
+	default:
+		if pred, err = t.Candidates(args, inword); err != nil {
+			return err
+		}
+	}
+	switch r.Shell {
+	case Zsh:
+		return writeZsh(w, pred)
+	case Fish:
+		return writeFish(w, pred)
+	case Pwsh:
+		return writePwsh(w, pred)
+	default:
+		return writeBash(w, pred, r.word())
+	}
+}
+
 //Compgen is the method required by the Argsgen interface
 func (t *Terminator) Compgen(args []string, inword bool) (comp []string, err error) {
+	cands, err := t.Candidates(args, inword)
+	return Values(cands), err
+}
 
-	//log.Printf("terminator %v inword:%t", args, inword)
-	// quick exit on non completion mode
-	if !IsCompletionMode() {
-		return
+// Candidates is the method required by the CandidateArgsgen interface
+func (t *Terminator) Candidates(args []string, inword bool) (comp []Candidate, err error) {
+	ctx := context.Background()
+	if t.debug != nil {
+		ctx = context.WithValue(ctx, debugKey{}, t.debug)
 	}
+	if t.timeout > 0 && os.Getenv(CACHE_REFRESH) == "" { // the cache refreshing process is not in a hurry
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+	return t.candidatesContext(ctx, args, inword)
+}
 
-	// hack the flagset to discard output and use a permissive parsing
-	t.fs.Init("terminators", flag.ContinueOnError)
-	t.fs.SetOutput(ioutil.Discard)
+// candidatesContext is the method required by the contextArgsgen interface
+//
+// Nested Terminators share the ctx of the top level one.
+func (t *Terminator) candidatesContext(ctx context.Context, args []string, inword bool) (comp []Candidate, err error) {
+
+	//log.Printf("terminator %v inword:%t", args, inword)
+	if t.matcher != nil {
+		ctx = context.WithValue(ctx, matcherKey{}, t.matcher)
+	}
+	m := matcherOf(ctx)
 
 	// compute a few reused vars
 	la := len(args)
@@ -143,16 +340,20 @@ func (t *Terminator) Compgen(args []string, inword bool) (comp []string, err err
 	}
 
 	// find out the completion case we are in
-	c := findCase(t.fs, args, inword)
-	//log.Printf("completing %v", c)
-	switch c {
+	// the Terminator flags are never parsed: the parsed ones are a copy (for concurrent completions)
+	var flags Flags
+	s := State{Args: args, Position: -1, Prefix: prefix, Inword: inword, Matcher: m}
+	s.Case, flags = findCase(t.flags, args, inword)
+	s.Flags, s.FlagSet = flags, flagSetOf(flags)
+	//log.Printf("completing %v", s.Case)
+	switch s.Case {
 
 	case CompErr:
 		err = errors.New("Invalid Flags")
 		return
 
 	case CompFlagKey:
-		return FlagNameGen(t.fs)(prefix), nil
+		return flagNameGen(flags, t.equals, m)(prefix), nil
 
 	case CompFlagVal:
 		// find out the key
@@ -165,43 +366,90 @@ func (t *Terminator) Compgen(args []string, inword bool) (comp []string, err err
 			}
 		}
 
-		// clean up the leading dashes
-		key = strings.TrimLeft(key, "-")
+		// the `-name=value` form: the key and the value are in the same word
+		eq := ""
+		if i := strings.Index(last, "="); inword && i >= 0 {
+			key, eq = last[:i], last[:i+1]
+			s.Prefix = last[i+1:]
+		}
+
+		// find out the flag name (the key can be a shorthand)
+		if f := flags.Lookup(key); f != nil {
+			key = f.Name
+		} else {
+			key = strings.TrimLeft(key, "-")
+		}
 
 		// ok the key is ready
+		s.Flag = key
 
 		//get the key compgen
-		if gen, exists := t.keyvalgen[key]; exists {
-			return gen(prefix), nil
-		} else { // uses the default based one
-			return FlagValueGen(t.fs, key)(prefix), nil
+		gen, exists := t.keyvalgen[key]
+		if !exists { // uses the default based one
+			gen = flagValueGen(flags, key, m).State().Context()
 		}
-		return
+		comp = collect(ctx, "flag -"+key, gen, s)
+		m.Rank(comp, s.Prefix)
+		// the `-name=` part is retained: the whole word is replaced
+		for i := range comp {
+			comp[i].Value = eq + comp[i].Value
+		}
+		return comp, nil
 
 	case CompArgs:
 		// there is no way to find out any compgen by default, I really need to rely on the one passed.
+		if a, ok := t.argsgen.(contextArgsgen); ok {
+			return a.candidatesContext(ctx, flags.Args(), inword)
+		}
 		if t.argsgen != nil {
-			return t.argsgen.Compgen(t.fs.Args(), inword)
+			return t.collectArgsgen(ctx, flags.Args(), inword)
 		}
 
-		if len(t.arggen) > 0 { // there are some positional arguments
-			// which is the current position?
-			position := t.fs.NArg()
-			if inword {
-				position--
-			}
-			if gen, exists := t.arggen[position]; exists {
-				return gen(prefix), nil
-			}
+		// which is the current position?
+		s.Position = len(flags.Args())
+		if inword {
+			s.Position--
 		}
-		return
+		if gen, exists := t.arggen[s.Position]; exists {
+			comp = collect(ctx, fmt.Sprintf("arg %d", s.Position), gen, s)
+		} else if t.varargsgen != nil {
+			comp = collect(ctx, "args", t.varargsgen, s)
+		}
+		m.Rank(comp, s.Prefix)
+		return comp, nil
 
 	default:
 		err = errors.New("Unknown case")
 		return
 
 	}
-	return
+}
+
+// collectArgsgen runs the Argsgen until it returns or the ctx is done.
+func (t *Terminator) collectArgsgen(ctx context.Context, args []string, inword bool) (comp []Candidate, err error) {
+	var mu sync.Mutex
+	gen := func(ctx context.Context, s State, emit func(Candidate)) {
+		var cands []Candidate
+		var e error
+		if a, ok := t.argsgen.(CandidateArgsgen); ok {
+			cands, e = a.Candidates(args, inword)
+		} else {
+			var values []string
+			values, e = t.argsgen.Compgen(args, inword)
+			cands = NewCandidates(values...)
+		}
+		mu.Lock()
+		err = e
+		mu.Unlock()
+		for _, c := range cands {
+			emit(c)
+		}
+	}
+	comp = collect(ctx, "argsgen", gen, State{})
+
+	mu.Lock()
+	defer mu.Unlock()
+	return comp, err
 }
 
 const (
@@ -227,34 +475,59 @@ func (c CompCase) String() string {
 	}
 }
 
-func findCase(fs *flag.FlagSet, args []string, inword bool) CompCase {
+// findCase returns the completion case, and the Flags parsed from the args (see Flags.Parse).
+func findCase(fs Flags, args []string, inword bool) (CompCase, Flags) {
 	//log.Printf("case for %v %v", args, inword)
 	la := len(args)
 	if la == 0 {
-		return CompArgs
+		return CompArgs, fs
+	}
+	if fs.GNU() {
+		return gnuCase(fs, args, inword)
 	}
 	endIsKey := strings.HasPrefix(args[la-1], "-")
 
-	err := fs.Parse(args[1:])
+	parsed, err := fs.Parse(args[1:])
+
+	// the `-name=value` form: completing the value part of an existing flag
+	eq := strings.Index(args[la-1], "=")
+	if endIsKey && eq >= 0 && inword {
+		if fs.Lookup(args[la-1][:eq]) == nil {
+			return CompErr, parsed
+		}
+		return CompFlagVal, parsed
+	}
 
 	if err != nil {
 		//log.Printf("flag parse err %v", err)
 		if endIsKey {
 			if inword {
-				return CompFlagKey
+				return CompFlagKey, parsed
 			} else {
-
-				// it depends on the key in fact. if the key is double ( bool ) or double (string)
-				//TODO handle the case of "bool": I need to lookup the flagset for args[la-1] and find out if it's a single or double flag
-				// and also check that it's not a single flag syntax (kinda -name=toto ) in which cas the output should be comperr
-				return CompFlagVal
+				if eq >= 0 { // `-name=value` is a complete but invalid flag
+					return CompErr, parsed
+				}
+				// the error must come from the last key, not from a previous one
+				if parsed, err = fs.Parse(args[1 : la-1]); err != nil {
+					return CompErr, parsed
+				}
+
+				// it depends on the key in fact. if the key is single ( bool ) or double (string)
+				f := fs.Lookup(args[la-1])
+				switch {
+				case f == nil:
+					return CompErr, parsed
+				case f.IsBool: // a bool flag never consumes the next word
+					return CompArgs, parsed
+				}
+				return CompFlagVal, parsed
 			}
 		}
 
-		return CompErr
+		return CompErr, parsed
 	}
 
-	remaining := fs.Args()
+	remaining := parsed.Args()
 	//log.Printf("rem=%v", remaining)
 	//log.Printf("err=%v parsed=%v -> %v", err, fs.Parsed(), remaining)
 	lr := len(remaining)
@@ -264,24 +537,113 @@ func findCase(fs *flag.FlagSet, args []string, inword bool) CompCase {
 		if inword {
 			// there is no args but i was completing a word, this word is either a valid key or a flag
 			if endIsKey {
-				return CompFlagKey
+				return CompFlagKey, parsed
 			}
-			return CompFlagVal
+			return CompFlagVal, parsed
 		}
 
 		// not in word so this a first arg
-		return CompArgs
+		return CompArgs, parsed
 
 	case lr == 1:
-		if endIsKey {
-			return CompFlagKey
+		if endIsKey && !afterSeparator(fs, args) {
+			return CompFlagKey, parsed
 		}
-		return CompArgs
+		return CompArgs, parsed
 
 	case lr > 0:
 		// there are not just only flags
-		return CompArgs
+		return CompArgs, parsed
+	}
+
+	return CompErr, parsed // unexpected outcome
+}
+
+// gnuCase is the findCase of GNU Flags, where flags and args can be interspersed (like `cmd arg -v --name n`).
+//
+// The words before the last one are walked, to find out if the last one is a flag value, an arg (after the `--`
+// separator), or else depends on its dash.
+func gnuCase(fs Flags, args []string, inword bool) (CompCase, Flags) {
+	la := len(args)
+	words := args[1:]
+	if inword {
+		words = args[1 : la-1]
+	}
+
+	pending := false // the next word is the value of a flag
+	separated := false
+	for _, a := range words {
+		switch {
+		case pending:
+			pending = false
+		case separated:
+		case a == "--":
+			separated = true
+		case strings.HasPrefix(a, "--"): // a long name
+			eq := strings.Index(a, "=")
+			key := a
+			if eq >= 0 {
+				key = a[:eq]
+			}
+			f := fs.Lookup(key)
+			if f == nil {
+				return CompErr, fs
+			}
+			pending = eq < 0 && !f.IsBool
+		case strings.HasPrefix(a, "-") && a != "-": // a cluster of shorthands
+			for j := 1; j < len(a); j++ {
+				f := fs.Lookup("-" + a[j:j+1])
+				if f == nil {
+					return CompErr, fs
+				}
+				if !f.IsBool { // it takes the rest of the word, or the next one
+					pending = j == len(a)-1
+					break
+				}
+			}
+		}
+	}
+
+	// the parsed flags, of the complete words if the last one is not valid yet
+	parsed, err := fs.Parse(args[1:])
+	if err != nil {
+		parsed, _ = fs.Parse(words)
+	}
+
+	last := args[la-1]
+	switch {
+	case pending:
+		return CompFlagVal, parsed
+	case !inword || separated:
+		return CompArgs, parsed
+	case !strings.HasPrefix(last, "-"):
+		return CompArgs, parsed
 	}
+	// the `--name=value` form: completing the value part of an existing flag (`-n=` is a cluster of shorthands)
+	if eq := strings.Index(last, "="); eq >= 0 && strings.HasPrefix(last, "--") {
+		if fs.Lookup(last[:eq]) == nil {
+			return CompErr, parsed
+		}
+		return CompFlagVal, parsed
+	}
+	return CompFlagKey, parsed
+}
+
+// flagSetOf returns the FlagSet of standard Flags, or nil
+func flagSetOf(flags Flags) *flag.FlagSet {
+	if f, ok := flags.(interface{ flagSet() *flag.FlagSet }); ok {
+		return f.flagSet()
+	}
+	return nil
+}
 
-	return CompErr // unexpected outcome
+// afterSeparator returns true if the last arg follows the `--` separator (all words after it are args)
+func afterSeparator(fs Flags, args []string) bool {
+	la := len(args)
+	if la < 3 || args[la-2] != "--" {
+		return false
+	}
+	// unless the `--` is the value of the previous flag
+	f := fs.Lookup(args[la-3])
+	return f == nil || f.IsBool || strings.Contains(args[la-3], "=")
 }
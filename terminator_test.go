@@ -1,8 +1,10 @@
 package compgen
 
 import (
+	"bytes"
 	"flag"
 	"io/ioutil"
+	"strconv"
 
 	"testing"
 )
@@ -16,6 +18,27 @@ func TestFindCase(t *testing.T) {
 	CheckCase(t, []string{"cmd", "-yes", "toto", "tata"}, true, CompArgs)
 	CheckCase(t, []string{"cmd", "-no", "toto", "tata"}, true, CompErr)
 
+	// the `-name=value` form
+	CheckCase(t, []string{"cmd", "-name="}, true, CompFlagVal)
+	CheckCase(t, []string{"cmd", "-name=to"}, true, CompFlagVal)
+	CheckCase(t, []string{"cmd", "-name=toto"}, false, CompArgs)
+	CheckCase(t, []string{"cmd", "-no=to"}, true, CompErr)
+	CheckCase(t, []string{"cmd", "-yes=t"}, true, CompFlagVal)
+	CheckCase(t, []string{"cmd", "-yes=x"}, false, CompErr)
+
+	// bool flags never consume the next word
+	CheckCase(t, []string{"cmd", "-yes"}, false, CompArgs)
+	CheckCase(t, []string{"cmd", "-name", "toto", "--yes"}, false, CompArgs)
+	CheckCase(t, []string{"cmd", "-force"}, false, CompArgs)
+	CheckCase(t, []string{"cmd", "-yes", "-name"}, false, CompFlagVal)
+	CheckCase(t, []string{"cmd", "-no", "-yes"}, false, CompErr)
+	CheckCase(t, []string{"cmd", "-no", "-name"}, false, CompErr)
+	CheckCase(t, []string{"cmd", "-no"}, false, CompErr)
+
+	// words after the `--` separator are args, unless it is a flag value
+	CheckCase(t, []string{"cmd", "--", "-"}, true, CompArgs)
+	CheckCase(t, []string{"cmd", "-yes", "--", "-na"}, true, CompArgs)
+	CheckCase(t, []string{"cmd", "-name", "--", "-"}, true, CompFlagKey)
 }
 
 func CheckCase(t *testing.T, args []string, inw bool, x CompCase) {
@@ -23,11 +46,62 @@ func CheckCase(t *testing.T, args []string, inw bool, x CompCase) {
 	fs := flag.NewFlagSet("t", flag.ContinueOnError)
 	fs.String("name", "name", "to set a name")
 	fs.Bool("yes", false, "to say yes")
+	fs.Var(new(forceValue), "force", "a custom bool flag")
 	fs.SetOutput(ioutil.Discard)
 
-	c := findCase(fs, args, inw)
+	c, _ := findCase(StdFlags(fs), args, inw)
 	if c != x {
 		t.Errorf("Invalid case %v %v: (%v vs %v)", args, inw, x, c)
 	}
 
 }
+
+// forceValue is a custom flag.Value that does not need a value
+type forceValue bool
+
+func (f *forceValue) String() string     { return strconv.FormatBool(bool(*f)) }
+func (f *forceValue) Set(s string) error { *f = forceValue(s == "true"); return nil }
+func (f *forceValue) IsBoolFlag() bool   { return true }
+
+func TestFlagEquals(t *testing.T) {
+	CheckTerminator(t, []string{"cmd", "-na"}, true, "-name")
+	CheckTerminator(t, []string{"cmd", "-name=t"}, true, "-name=toto", "-name=tata", "-name=titi")
+	CheckTerminator(t, []string{"cmd", "-name=to"}, true, "-name=toto")
+	CheckTerminator(t, []string{"cmd", "--name="}, true, "--name=toto", "--name=tata", "--name=titi")
+
+	term := newTerminator()
+	term.FlagEquals(true)
+	comp, _ := term.Compgen([]string{"cmd", "-"}, true)
+	if x := []string{"-name=", "-yes"}; !EqStrings(comp, x) {
+		t.Errorf("Invalid flag names %v vs %v", comp, x)
+	}
+}
+
+func TestWriteBash(t *testing.T) {
+	var buf bytes.Buffer
+	writeBash(&buf, NewCandidates("-name=toto", "-name=tata"), Arg{Val: "-name=t"})
+	if x := "toto\ntata\n"; buf.String() != x {
+		t.Errorf("Invalid bash output %q vs %q", buf.String(), x)
+	}
+}
+
+// newTerminator returns a Terminator for the same flagset as CheckCase
+func newTerminator() *Terminator {
+	fs := flag.NewFlagSet("t", flag.ContinueOnError)
+	fs.String("name", "name", "to set a name")
+	fs.Bool("yes", false, "to say yes")
+
+	term := NewTerminator(fs)
+	term.Flag("name", ValueGen([]string{"toto", "tata", "titi"}))
+	return term
+}
+
+func CheckTerminator(t *testing.T, args []string, inword bool, x ...string) {
+	comp, err := newTerminator().Compgen(args, inword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqStrings(comp, x) {
+		t.Errorf("Invalid completion for %v %v: %v vs %v", args, inword, comp, x)
+	}
+}
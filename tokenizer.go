@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"strings"
 	"unicode"
 
 	"io"
@@ -15,9 +16,37 @@ var (
 
 //Arg is the result of parsing a full command line
 type Arg struct {
-	Val    string
-	Offset int // position in the original
-	Length int // length occupied in the original
+	Val        string
+	Offset     int  // position in the original, in runes
+	Length     int  // length occupied in the original, in runes
+	ByteOffset int  // position in the original, in bytes (like $COMP_POINT)
+	ByteLength int  // length occupied in the original, in bytes
+	Quote      rune // the open quote (' or ") if the arg ends within unterminated quotes, '\\' if it contains backslash escapes, 0 otherwise
+	QuoteAt    int  // the length of Val before the open quote, in bytes, if the arg ends within unterminated quotes
+	Op         bool // true if the arg is a control or redirection operator (like "|" or "2>"), see operators
+}
+
+// operators are the bash control operators (false), and redirection operators (true): they end words, even without spaces.
+//
+// Redirection operators can be prefixed by a file descriptor (like "2>").
+var operators = map[string]bool{
+	"|": false, "||": false, "|&": false, "&": false, "&&": false,
+	";": false, ";;": false, ";&": false, "(": false, ")": false,
+	"<": true, "<<": true, "<<-": true, "<<<": true, "<&": true, "<>": true,
+	">": true, ">>": true, ">|": true, ">&": true, "&>": true, "&>>": true,
+}
+
+const digits = "0123456789"
+
+// isRedirection returns true if the operator is a redirection (like "2>"), followed by its target
+func isRedirection(op string) bool {
+	return operators[strings.TrimLeft(op, digits)]
+}
+
+// isOperator returns true if the rune starts an operator
+func isOperator(r rune) bool {
+	_, ok := operators[string(r)]
+	return ok
 }
 
 func NewArg(val string, offset, length int) Arg {
@@ -35,7 +64,7 @@ func Tokenize(reader io.RuneScanner) (args []Arg, err error) {
 
 	for {
 		//read one and deal with errors
-		r, _, err := reader.ReadRune()
+		r, size, err := reader.ReadRune()
 		printf("rune %4s ", fmt.Sprintf("%q", string(r)))
 		if err != nil && err != io.EOF {
 			return args, err
@@ -51,7 +80,7 @@ func Tokenize(reader io.RuneScanner) (args []Arg, err error) {
 		case err == io.EOF: // end of an arg. need to cut the arg
 			printf("%12s : ", "Is EOF")
 			//fire the tokens
-			if state.initpos > 0 {
+			if state.initpos >= 0 {
 
 				a := state.Pull()
 				printf("Pull %v\n", a)
@@ -68,7 +97,7 @@ func Tokenize(reader io.RuneScanner) (args []Arg, err error) {
 			default:
 				//any other case are fine
 				state.InDollar = false // moving out of this state
-				state.Move(-1)         //move back (caveat: we know that the position will always be moved )
+				state.Move(-1, -size)  //move back (caveat: we know that the position will always be moved )
 				reader.UnreadRune()    // this character does not belong to us rewing
 			}
 
@@ -131,6 +160,7 @@ func Tokenize(reader io.RuneScanner) (args []Arg, err error) {
 		case state.InEscape:
 			state.InEscape = false
 			state.InSeparator = false
+			state.escaped = true
 			printf("%15s : %s\n", "Esc", "Push")
 			//psuh the rune but I need to push it like if it has started one char before (the \)
 			state.Push(r)
@@ -143,12 +173,16 @@ func Tokenize(reader io.RuneScanner) (args []Arg, err error) {
 				printf("%15s : %s\n", "Sep Cont", "Consume")
 				//just consume it
 
+			case isOperator(r):
+				printf("%15s : %s\n", "Operator", "Pull")
+				args = append(args, state.Operator(reader, r, size))
+
 			case !unicode.IsSpace(r): // end of separator
 				printf("%15s : ", "Sep End")
 				state.InSeparator = false
-				state.initpos = state.pos
-				state.Move(-1)      //move back (caveat: we know that the position will always be moved )
-				reader.UnreadRune() // this character does not belong to us rewing
+				state.initpos, state.initbyte = state.pos, state.bytepos
+				state.Move(-1, -size) //move back (caveat: we know that the position will always be moved )
+				reader.UnreadRune()   // this character does not belong to us rewing
 
 			}
 
@@ -165,10 +199,12 @@ func Tokenize(reader io.RuneScanner) (args []Arg, err error) {
 			case r == '\'':
 				printf("%15s : %s\n", "SingleQuote Start", "Cons")
 				state.InSingleQuote = true
+				state.quoteat = state.buf.Len()
 
 			case r == '"':
 				printf("%15s : %s\n", "DoubleQuote Start", "Cons")
 				state.InDoubleQuote = true
+				state.quoteat = state.buf.Len()
 
 			case r == '\\':
 				printf("%15s : %s\n", "Escape Start", "Cons")
@@ -187,13 +223,26 @@ func Tokenize(reader io.RuneScanner) (args []Arg, err error) {
 				args = append(args, a)
 				state.InSeparator = true
 
+			case isOperator(r):
+				printf("%15s : ", "Operator")
+				// the word is over, unless it is the file descriptor of a redirection (like "2>")
+				if !state.IsFd(r) {
+					a := state.Pull()
+					printf("Pull %v ", a)
+					args = append(args, a)
+				}
+				a := state.Operator(reader, r, size)
+				printf("Pull %v\n", a)
+				args = append(args, a)
+				state.InSeparator = true
+
 			default:
 				printf("%15s : %s\n", "Default", "Push")
 				state.Push(r)
 			}
 		}
 		//alway move the position
-		state.Move(1)
+		state.Move(1, size)
 
 	}
 }
@@ -203,33 +252,85 @@ type lexstate struct {
 	//initpos = pos when an arg has started
 	//pos the current pos
 	initpos, pos                 int
+	initbyte, bytepos            int // the same, in bytes
 	InSingleQuote, InDoubleQuote bool
 	InSeparator                  bool
 	InDoubleQuoteEscape          bool
 	InEscape, InDollar           bool
+	escaped                      bool // a backslash escape has been found in the arg
+	quoteat                      int  // the length of the arg in progress when the last quote opened
 }
 
 func newstate() lexstate {
 	return lexstate{
-		buf: new(bytes.Buffer),
+		buf:         new(bytes.Buffer),
+		initpos:     -1,   // no arg started yet
+		InSeparator: true, // leading spaces are not part of any arg
 	}
 }
 
 //Pull get an arg out of the lexstate and reset the state
-func (l *lexstate) Move(size int) {
-	l.pos += size
+func (l *lexstate) Move(runes, bytes int) {
+	l.pos += runes
+	l.bytepos += bytes
 }
 
 func (l *lexstate) Push(r rune) {
 	if l.initpos < 0 {
-		l.initpos = l.pos
+		l.initpos, l.initbyte = l.pos, l.bytepos
 	}
 	l.buf.WriteRune(r)
 }
+
+// IsFd returns true if the arg in progress is the file descriptor of the redirection operator starting with r
+func (l *lexstate) IsFd(r rune) bool {
+	fd := l.buf.String()
+	return (r == '<' || r == '>') && fd != "" && strings.Trim(fd, digits) == "" && !l.escaped
+}
+
+// Operator reads the longest operator starting with r (see operators), and returns it as an Arg.
+//
+// The arg in progress, if any, is its file descriptor (see IsFd).
+func (l *lexstate) Operator(reader io.RuneScanner, r rune, size int) Arg {
+	if l.initpos < 0 {
+		l.initpos, l.initbyte = l.pos, l.bytepos
+	}
+	l.buf.WriteRune(r)
+	for {
+		n, s, err := reader.ReadRune()
+		if err != nil {
+			break
+		}
+		if _, ok := operators[strings.TrimLeft(l.buf.String()+string(n), digits)]; !ok {
+			reader.UnreadRune() // this character does not belong to us rewind
+			break
+		}
+		l.buf.WriteRune(n)
+		l.Move(1, s)
+		size = s
+	}
+	// the position is moved past the last rune by the caller
+	l.Move(1, size)
+	a := l.Pull()
+	a.Op = true
+	l.Move(-1, -size)
+	return a
+}
+
 func (l *lexstate) Pull() Arg {
 	length := l.pos - l.initpos
 	a := NewArg(l.buf.String(), l.initpos, length)
+	a.ByteOffset, a.ByteLength = l.initbyte, l.bytepos-l.initbyte
+	switch {
+	case l.InSingleQuote:
+		a.Quote, a.QuoteAt = '\'', l.quoteat
+	case l.InDoubleQuote:
+		a.Quote, a.QuoteAt = '"', l.quoteat
+	case l.escaped:
+		a.Quote = '\\'
+	}
 	l.buf.Reset()
 	l.initpos = -1
+	l.escaped = false
 	return a
 }
@@ -9,6 +9,9 @@ var bench = map[string][]Arg{
 	//random example found on the web
 	// or to help debugging a particular case
 	`ab cd `:             []Arg{NewArg("ab", 0, 2), NewArg("cd", 3, 2)},
+	`abc`:                []Arg{NewArg("abc", 0, 3)},
+	` ab`:                []Arg{NewArg("ab", 1, 2)},
+	`''`:                 []Arg{NewArg("", 0, 2)},
 	"a\u2345b cd":        []Arg{NewArg("a\u2345b", 0, 3), NewArg("cd", 4, 2)},
 	`ab cd`:              []Arg{NewArg("ab", 0, 2), NewArg("cd", 3, 2)},
 	`'ab' 'cd'`:          []Arg{NewArg("ab", 0, 4), NewArg("cd", 5, 4)},
@@ -87,6 +90,30 @@ func TestSimpleLexer(t *testing.T) {
 	chk(t, `'ab' 'cd'`)
 }
 
+// the first word used to be dropped when it was the only one, and leading spaces used to start an empty word
+func TestLexerFirstWord(t *testing.T) {
+	for line, x := range map[string][]string{
+		"":       {},
+		"  ":     {},
+		"abc":    {"abc"},
+		"  abc":  {"abc"},
+		"'a b'":  {"a b"},
+		" ab cd": {"ab", "cd"},
+	} {
+		args, err := Tokenize(strings.NewReader(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		vals := make([]string, len(args))
+		for i, a := range args {
+			vals[i] = a.Val
+		}
+		if len(vals) != len(x) || strings.Join(vals, ",") != strings.Join(x, ",") {
+			t.Errorf("Invalid args for %q: %q vs %q", line, vals, x)
+		}
+	}
+}
+
 func chk(t *testing.T, k string) {
 	CheckLexer(t, k, bench[k])
 }
@@ -127,3 +154,68 @@ func Min(a, b int) int {
 	}
 	return b
 }
+
+func TestLexerQuote(t *testing.T) {
+	for line, x := range map[string]rune{
+		`cmd "my fi`:     '"',
+		`cmd 'my fi`:     '\'',
+		`cmd "`:          '"',
+		`cmd my\ fi`:     '\\',
+		`cmd "my" fi`:    0,
+		`cmd "my fi"`:    0,
+		`cmd 'a b'"c`:    '"',
+		`cmd "a\"b`:      '"',
+		`cmd "a b"\ c`:   '\\',
+		`cmd "a b" c\ d`: '\\',
+	} {
+		args, err := Tokenize(strings.NewReader(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q := args[len(args)-1].Quote; q != x {
+			t.Errorf("Invalid quote for %q: %q vs %q", line, q, x)
+		}
+		if args[0].Quote != 0 {
+			t.Errorf("Invalid quote for the command in %q: %q", line, args[0].Quote)
+		}
+	}
+}
+
+func TestLexerOperators(t *testing.T) {
+	for line, x := range map[string][]string{
+		"a|b":          {"a", "|", "b"},
+		"a || b":       {"a", "||", "b"},
+		"a&&b;c":       {"a", "&&", "b", ";", "c"},
+		"a >>b":        {"a", ">>", "b"},
+		"a 2>&1":       {"a", "2>&", "1"},
+		"a2>b":         {"a2", ">", "b"},
+		"a &>> b":      {"a", "&>>", "b"},
+		"(a)":          {"(", "a", ")"},
+		`a '|' \; ">"`: {"a", "|", ";", ">"},
+	} {
+		args, err := Tokenize(strings.NewReader(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		vals := make([]string, len(args))
+		for i, a := range args {
+			vals[i] = a.Val
+		}
+		if !EqStrings(vals, x) {
+			t.Errorf("Invalid args for %q: %q vs %q", line, vals, x)
+		}
+	}
+
+	args, _ := Tokenize(strings.NewReader("a 2>>é|b"))
+	for i, x := range []Arg{
+		{Val: "a", Offset: 0, Length: 1, ByteOffset: 0, ByteLength: 1},
+		{Val: "2>>", Offset: 2, Length: 3, ByteOffset: 2, ByteLength: 3, Op: true},
+		{Val: "é", Offset: 5, Length: 1, ByteOffset: 5, ByteLength: 2},
+		{Val: "|", Offset: 6, Length: 1, ByteOffset: 7, ByteLength: 1, Op: true},
+		{Val: "b", Offset: 7, Length: 1, ByteOffset: 8, ByteLength: 1},
+	} {
+		if i >= len(args) || args[i] != x {
+			t.Errorf("Invalid arg %d: %+v vs %+v", i, args, x)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package compgen
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+/*
+this file contains the command line unwrapping, to find out the real command behind env assignments and wrapper commands (like `sudo cmd`).
+*/
+
+// DefaultWrappers returns the default wrappers of a Terminator (see Terminator.Wrappers): sudo, env, time, nice,
+// xargs, command, exec and nohup.
+//
+// The map is a copy, other wrappers can be added to it:
+//
+//	wrappers := compgen.DefaultWrappers()
+//	wrappers["chronic"] = nil
+//	t.Wrappers(wrappers)
+func DefaultWrappers() map[string][]string {
+	wrappers := make(map[string][]string, len(defaultWrappers))
+	for name, opts := range defaultWrappers {
+		wrappers[name] = append([]string(nil), opts...)
+	}
+	return wrappers
+}
+
+// defaultWrappers are the commands that run another command, mapped to their options that need a value
+var defaultWrappers = map[string][]string{
+	"sudo":    {"-C", "-D", "-g", "-h", "-p", "-R", "-r", "-T", "-t", "-U", "-u", "--chdir", "--chroot", "--close-from", "--group", "--host", "--prompt", "--role", "--type", "--command-timeout", "--other-user", "--user"},
+	"env":     {"-C", "-S", "-u", "--chdir", "--split-string", "--unset"},
+	"time":    {"-f", "-o", "--format", "--output"},
+	"nice":    {"-n", "--adjustment"},
+	"xargs":   {"-a", "-d", "-E", "-I", "-L", "-n", "-P", "-s", "--arg-file", "--delimiter", "--max-args", "--max-chars", "--max-procs", "--process-slot-var"},
+	"command": nil,
+	"exec":    {"-a"},
+	"nohup":   nil,
+}
+
+// unwrap returns the index of the real command in args: leading env assignments (like `FOO=1`),
+// and wrappers with their options are skipped. Wrappers match by base name (like `/usr/bin/sudo`).
+//
+// The word being completed is never skipped (inword is like in ParseLine). 'ok' is false if it is not after
+// the real command: it is the command itself, or a wrapper option (like `sudo -u ro`).
+func unwrap(args []string, inword bool, wrappers map[string][]string) (i int, ok bool) {
+	n := len(args)
+	if inword {
+		n-- // the last word
+	}
+	for i < n {
+		switch opts, wrapper := wrappers[filepath.Base(args[i])]; {
+		case isAssignment(args[i]):
+			i++
+		case wrapper:
+			i = skipOptions(args, i+1, n, opts)
+		default:
+			return i, true
+		}
+	}
+	if i > n { // the last option needs a value
+		i = n
+	}
+	return i, i == 0 // there is no word before the cursor, only the command: like without wrappers
+}
+
+// skipOptions returns the index of the first word after the options, starting at i, but before n.
+func skipOptions(args []string, i, n int, opts []string) int {
+	for ; i < n; i++ {
+		a := args[i]
+		switch {
+		case a == "--": // the end of options
+			return i + 1
+		case !strings.HasPrefix(a, "-") || a == "-":
+			return i
+		case strings.HasPrefix(a, "--"):
+			if !strings.Contains(a, "=") && needsValue(opts, a) {
+				i++
+			}
+		default: // a cluster of shorthands, the first that needs a value takes the rest of the word, or the next one
+			for j := 1; j < len(a); j++ {
+				if needsValue(opts, "-"+a[j:j+1]) {
+					if j == len(a)-1 {
+						i++
+					}
+					break
+				}
+			}
+		}
+	}
+	return i
+}
+
+// needsValue returns true if the option is among the options that need a value
+func needsValue(opts []string, opt string) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// isAssignment returns true if the word is an env assignment, like `NAME=value`
+func isAssignment(word string) bool {
+	eq := strings.Index(word, "=")
+	if eq <= 0 {
+		return false
+	}
+	for i, r := range word[:eq] {
+		if r != '_' && !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || i > 0 && '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package compgen
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUnwrap(t *testing.T) {
+	testUnwrap(t, "FOO=1 tester -na", []string{"tester", "-na"}, true)
+	testUnwrap(t, "FOO=1 BAR='a b' tester ", []string{"tester"}, false)
+	testUnwrap(t, "FOO=1 sudo -E tester -na", []string{"tester", "-na"}, true)
+	testUnwrap(t, "sudo -u root tester -na", []string{"tester", "-na"}, true)
+	testUnwrap(t, "sudo -Eu root --group=adm tester -na", []string{"tester", "-na"}, true)
+	testUnwrap(t, "sudo -uroot tester -na", []string{"tester", "-na"}, true)
+	testUnwrap(t, "time nice -n 5 env -i A=1 tester a", []string{"tester", "a"}, true)
+	testUnwrap(t, "xargs -0 -I {} -- tester ", []string{"tester"}, false)
+	testUnwrap(t, "find . | xargs tester -na", []string{"tester", "-na"}, true)
+	testUnwrap(t, "tester FOO=1 sudo", []string{"tester", "FOO=1", "sudo"}, true)
+	testUnwrap(t, "command tester -na", []string{"tester", "-na"}, true)
+	testUnwrap(t, "exec -a name nohup tester -na", []string{"tester", "-na"}, true)
+	testUnwrap(t, "/usr/bin/sudo -u root tester -na", []string{"tester", "-na"}, true)
+
+	testUnwrap(t, "tes", []string{"tes"}, false)
+
+	// the word being completed is never skipped, but it is not after the command
+	testNoCommand(t, "FOO=1 tes")
+	testNoCommand(t, "sudo -u ro")
+	testNoCommand(t, "sudo -u ")
+	testNoCommand(t, "sudo ")
+	testNoCommand(t, "FOO=1 sudo -E ")
+}
+
+// testUnwrap checks the args of the line, once unwrapped by the DefaultWrappers, like Terminator.Complete does
+func testUnwrap(t *testing.T, line string, xargs []string, xinword bool) {
+	args, inword, err := ParseLine(line, len(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, ok := unwrap(args, inword, DefaultWrappers())
+	args = args[i:]
+	inword = inword && len(args) > 1
+	if !ok || inword != xinword || !EqStrings(args, xargs) {
+		t.Errorf("Invalid unwrapped args for %q: (%v,%v,%v) vs (%v,%v,true)", line, args, inword, ok, xargs, xinword)
+	}
+}
+
+// testNoCommand checks that the cursor is not after the command, once unwrapped by the DefaultWrappers
+func testNoCommand(t *testing.T, line string) {
+	args, inword, err := ParseLine(line, len(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i, ok := unwrap(args, inword, DefaultWrappers()); ok {
+		t.Errorf("Invalid unwrapped args for %q: %v after the command", line, args[i:])
+	}
+}
+
+func TestTerminatorWrappers(t *testing.T) {
+	for _, c := range []struct {
+		wrappers map[string][]string
+		r        Request
+		x        string
+	}{
+		{DefaultWrappers(), Request{Shell: Bash, Line: "sudo -u root cmd -name=t", Point: 24}, "toto\ntata\ntiti\n"},
+		{DefaultWrappers(), Request{Shell: Zsh, Line: "sudo\n-E\ncmd\n-name=t", Point: 4}, "\t-name=toto\n\t-name=tata\n\t-name=titi\n"},
+		{map[string][]string{"chronic": nil}, Request{Shell: Bash, Line: "chronic cmd -name=t", Point: 19}, "toto\ntata\ntiti\n"},
+		{nil, Request{Shell: Bash, Line: "FOO=1 cmd -name=t", Point: 17}, "toto\ntata\ntiti\n"},
+		// on the command itself, or a wrapper option
+		{DefaultWrappers(), Request{Shell: Bash, Line: "sudo -u ro", Point: 10}, ""},
+		{DefaultWrappers(), Request{Shell: Bash, Line: "sudo -u ", Point: 8}, ""},
+		{DefaultWrappers(), Request{Shell: Bash, Line: "FOO=1 cm", Point: 8}, ""},
+		{DefaultWrappers(), Request{Shell: Zsh, Line: "sudo\n", Point: 2}, ""},
+	} {
+		term := newTerminator()
+		term.Wrappers(c.wrappers)
+		var out bytes.Buffer
+		term.Complete(&out, c.r)
+		if out.String() != c.x {
+			t.Errorf("Invalid output for %q: %q vs %q", c.r.Line, out.String(), c.x)
+		}
+	}
+
+	// sudo is not a wrapper anymore
+	if i, _ := unwrap([]string{"sudo", "-u", "root", "cmd"}, false, map[string][]string{"chronic": nil}); i != 0 {
+		t.Errorf("Invalid unwrap index %v vs 0", i)
+	}
+}
+
+func TestDefaultWrappers(t *testing.T) {
+	// the defaults are copied
+	DefaultWrappers()["sudo"][0] = "-x"
+	delete(DefaultWrappers(), "env")
+	if w := DefaultWrappers(); w["sudo"][0] != "-C" || w["env"] == nil {
+		t.Errorf("Invalid default wrappers %v", w)
+	}
+}
+
+func TestIsAssignment(t *testing.T) {
+	for word, x := range map[string]bool{
+		"FOO=1":   true,
+		"_f00=":   true,
+		"A=b=c":   true,
+		"=1":      false,
+		"1FOO=1":  false,
+		"-name=1": false,
+		"a.b=1":   false,
+		"FOO":     false,
+	} {
+		if a := isAssignment(word); a != x {
+			t.Errorf("Invalid assignment for %q: %v vs %v", word, a, x)
+		}
+	}
+}
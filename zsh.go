@@ -0,0 +1,137 @@
+package compgen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+this file contains the zsh completion protocol.
+
+zsh does not export the command line to external commands, so the hook
+script (see ZshScript) passes the `words` array and the `CURRENT` index
+through the environment instead.
+*/
+
+const (
+	ZSH_WORDS   = "COMP_ZSH_WORDS"   // newline separated $words
+	ZSH_CURRENT = "COMP_ZSH_CURRENT" // $CURRENT, the one-indexed word under the cursor
+)
+
+var (
+	ErrInvalidCurrent = errors.New("Invalid zsh CURRENT word index")
+)
+
+// IsZshCompletionMode returns true if the execution has been made by the zsh hook script.
+//
+// More specifically:
+//
+// Returns true iif "COMP_ZSH_WORDS" and "COMP_ZSH_CURRENT" are set to a non empty value
+func IsZshCompletionMode() bool {
+	return os.Getenv(ZSH_WORDS) != "" && os.Getenv(ZSH_CURRENT) != ""
+}
+
+// ZshArgs read the completion words ($COMP_ZSH_WORDS) and current index ($COMP_ZSH_CURRENT) from env
+// and returns the same values as Args does for bash.
+func ZshArgs() (args []string, inword bool, err error) {
+	current, err := strconv.Atoi(os.Getenv(ZSH_CURRENT))
+	if err != nil {
+		return
+	}
+	return zshArgs(strings.Split(os.Getenv(ZSH_WORDS), "\n"), current)
+}
+
+// zshArgs converts zsh words and current into args.
+//
+// words are the raw words as typed in the command line (quotes included), they are unquoted using Tokenize.
+// Words after the current one are NOT returned.
+func zshArgs(words []string, current int) (args []string, inword bool, err error) {
+	if current < 1 || current > len(words) {
+		err = ErrInvalidCurrent
+		return
+	}
+	words = words[:current]
+
+	// the current word is empty when the cursor is not inside a word: "toto <TAB>"
+	inword = words[current-1] != ""
+	if !inword {
+		words = words[:current-1]
+	}
+
+	args = make([]string, len(words))
+	for i, w := range words {
+		if args[i], err = unquote(w); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// unquote removes the shell quoting of a single word.
+func unquote(word string) (string, error) {
+	aargs, err := Tokenize(strings.NewReader(word))
+	if err != nil {
+		return "", err
+	}
+	vals := make([]string, len(aargs))
+	for i, a := range aargs {
+		vals[i] = a.Val
+	}
+	return strings.Join(vals, " "), nil
+}
+
+// writeZsh writes candidates in the `_describe` format, prefixed by their group: one "group\tvalue:description" per line.
+func writeZsh(w io.Writer, cands []Candidate) error {
+	for _, c := range cands {
+		line := strings.Replace(c.Value, ":", `\:`, -1)
+		if c.Description != "" {
+			line += ":" + strings.Replace(c.Description, "\n", " ", -1)
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", c.Group, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ZshScript writes the zsh script that registers 'cmd' as self completing.
+//
+// The script can be either sourced, or saved as `_cmd` in any directory of the $fpath.
+func ZshScript(w io.Writer, cmd string) error {
+	_, err := fmt.Fprintf(w, zshScript, cmd, ZSH_WORDS, ZSH_CURRENT, ScriptPath(Zsh, cmd))
+	return err
+}
+
+const zshScript = `#compdef %[1]s
+# zsh completion for %[1]s
+# save it as %[4]s (or any directory of the $fpath), or source it from ~/.zshrc
+
+_%[1]s() {
+	local -a lines candidates nospace
+	local group line tab=$'\t'
+	lines=(${(f)"$(%[3]s=$CURRENT %[2]s="${(pj:\n:)words}" %[1]s 2>/dev/null)"})
+	for group in "${(@u)lines%%%%${tab}*}"; do
+		candidates=() nospace=()
+		for line in ${${(M)lines:#${(b)group}${tab}*}#*${tab}}; do
+			# no space after "-name=" or a directory, to complete further
+			if [[ ${line%%%%:*} == *[=/] ]]; then
+				nospace+=("$line")
+			else
+				candidates+=("$line")
+			fi
+		done
+		_describe -t "${group:-values}" "${group:-%[1]s}" candidates -U
+		_describe -t "${group:-values}" "${group:-%[1]s}" nospace -U -S ''
+	done
+}
+
+if [ "$funcstack[1]" = "_%[1]s" ]; then
+	_%[1]s "$@"
+else
+	compdef _%[1]s %[1]s
+fi
+`
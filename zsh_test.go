@@ -0,0 +1,58 @@
+package compgen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestZshArgs(t *testing.T) {
+	testZshArgs(t, []string{"tester", "toto"}, 2, []string{"tester", "toto"}, true)
+	testZshArgs(t, []string{"tester", "toto", ""}, 3, []string{"tester", "toto"}, false)
+	testZshArgs(t, []string{"tester", "toto", "tata"}, 2, []string{"tester", "toto"}, true)
+	testZshArgs(t, []string{"tester", `"my fi`}, 2, []string{"tester", "my fi"}, true)
+	testZshArgs(t, []string{"tester", `a\ b`, ""}, 3, []string{"tester", "a b"}, false)
+}
+
+func testZshArgs(t *testing.T, words []string, current int, xargs []string, xinwords bool) {
+	args, inwords, err := zshArgs(words, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inwords != xinwords || !EqStrings(args, xargs) {
+		t.Errorf("Invalid zsh Args parsing (%v,%v) vs (%v,%v)", args, inwords, xargs, xinwords)
+	}
+}
+
+func TestZshArgsInvalidCurrent(t *testing.T) {
+	if _, _, err := zshArgs([]string{"tester"}, 2); err != ErrInvalidCurrent {
+		t.Errorf("Invalid error %v vs %v", err, ErrInvalidCurrent)
+	}
+}
+
+func TestWriteZsh(t *testing.T) {
+	var buf bytes.Buffer
+	writeZsh(&buf, []Candidate{
+		{Value: "-name", Description: "to set a name", Kind: KindFlag},
+		{Value: "a:b", Group: "values"},
+	})
+
+	x := "\t-name:to set a name\nvalues\ta\\:b\n"
+	if buf.String() != x {
+		t.Errorf("Invalid zsh output %q vs %q", buf.String(), x)
+	}
+}
+
+func TestZshScript(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ZshScript(&buf, "tester"); err != nil {
+		t.Fatal(err)
+	}
+	script := buf.String()
+	for _, x := range []string{"#compdef tester", "_describe", "compdef _tester tester", ZSH_WORDS, ZSH_CURRENT} {
+		if !strings.Contains(script, x) {
+			t.Errorf("zsh script does not contain %q", x)
+		}
+	}
+}